@@ -14,7 +14,10 @@ import (
 	"time"
 
 	"github.com/IAmRadek/go-kit/envconfig"
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/algorithms/bnb"
 	"github.com/IAmRadek/packing/internal/algorithms/dp"
+	"github.com/IAmRadek/packing/internal/algorithms/greedy"
 	"github.com/IAmRadek/packing/internal/app/allocation"
 	"github.com/IAmRadek/packing/internal/app/inventory"
 	"github.com/IAmRadek/packing/internal/domain/pack"
@@ -34,6 +37,27 @@ type Config struct {
 	IdleTimeout              time.Duration `env:"IDLE_TIMEOUT" default:"10s"`
 	MaxHeaderBytes           int           `env:"MAX_HEADER_BYTES" default:"1024"`
 	GracefulShutdownDuration time.Duration `env:"GRACEFUL_SHUTDOWN_DURATION" default:"5s"`
+	AllocationMaxCells       int64         `env:"ALLOCATION_MAX_CELLS" default:"20000000"`
+	AllocationMaxDuration    time.Duration `env:"ALLOCATION_MAX_DURATION" default:"5s"`
+	// Algorithm is the allocator used by inventories that do not pick one: dp, bnb or greedy.
+	Algorithm string `env:"ALGORITHM" default:"dp"`
+	// FastPath tries the greedy allocator first and keeps its answer when it is within the gaps below.
+	FastPath            bool  `env:"FAST_PATH" default:"false"`
+	FastPathOverfillGap int64 `env:"FAST_PATH_OVERFILL_GAP" default:"0"`
+	FastPathPacksGap    int64 `env:"FAST_PATH_PACKS_GAP" default:"0"`
+	FastPathCostGap     int64 `env:"FAST_PATH_COST_GAP" default:"0"`
+	FastPathVerifyUpTo  int64 `env:"FAST_PATH_VERIFY_UP_TO" default:"10000"`
+	// Strict rejects allocations breaking the rules of their sizes, demands up to StrictOptimalUpTo are
+	// also compared with brute force.
+	Strict            bool  `env:"STRICT" default:"false"`
+	StrictOptimalUpTo int64 `env:"STRICT_OPTIMAL_UP_TO" default:"0"`
+	// ShadowAlgorithm, when set, runs that allocator next to the served one and records where they disagree.
+	ShadowAlgorithm   string `env:"SHADOW_ALGORITHM" default:""`
+	ShadowConcurrency int    `env:"SHADOW_CONCURRENCY" default:"4"`
+	ShadowKeep        int    `env:"SHADOW_KEEP" default:"50"`
+	// ShipmentMaxWeight (g) and ShipmentMaxVolume (cm³) cap a shipment when a request gives no limits, zero is unlimited.
+	ShipmentMaxWeight int64 `env:"SHIPMENT_MAX_WEIGHT" default:"0"`
+	ShipmentMaxVolume int64 `env:"SHIPMENT_MAX_VOLUME" default:"0"`
 }
 
 func main() {
@@ -60,11 +84,25 @@ func main() {
 	}
 
 	dec := schema.NewDecoder()
+	// Note: empty entries of per-size fields, such as unknown dimensions, keep their place in the slices.
+	dec.ZeroEmpty(true)
 
 	log.Info("Templates Loaded", "templates", render.Templates())
 
 	dpAlgo := dp.Allocator{}
 
+	registry := algorithms.NewRegistry()
+	registry.Register("dp", dpAlgo)
+	registry.Register("bnb", bnb.Allocator{})
+	registry.Register("greedy", greedy.Allocator{
+		Verify:     dpAlgo,
+		VerifyUpTo: cfg.FastPathVerifyUpTo,
+	})
+	if err := registry.SetDefault(cfg.Algorithm); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "choosing default algorithm: %v", err)
+		return
+	}
+
 	memRepo := infra.NewMemoryRepo()
 
 	inv := pack.NewInventory("tires", pack.Sizes{
@@ -72,29 +110,98 @@ func main() {
 			ID:       "S",
 			Capacity: 23,
 			Label:    "S",
+			Stock:    1000,
+			Price:    250,
 		},
 		pack.Size{
 			ID:       "L",
 			Capacity: 31,
 			Label:    "L",
+			Stock:    1000,
+			Price:    320,
 		},
 		pack.Size{
 			ID:       "XL",
 			Capacity: 53,
 			Label:    "XL",
+			Stock:    300,
+			Price:    490,
 		},
 	})
+	inv.TrackStock(true)
 	memRepo.Save(ctx, inv)
+	memRepo.SaveLocations(ctx, inv.SKU(), pack.Locations{
+		{ID: "north", ShippingCost: 1500, Stock: map[pack.ID]pack.Quantity{"S": 400, "L": 200, "XL": 100}},
+		{ID: "south", ShippingCost: 900, Stock: map[pack.ID]pack.Quantity{"S": 100, "L": 600}},
+		{ID: "east", ShippingCost: 2500, Stock: map[pack.ID]pack.Quantity{"XL": 200}},
+	})
+
+	rims := pack.NewInventory("rims", pack.Sizes{
+		pack.Size{
+			ID:       "R4",
+			Capacity: 4,
+			Label:    "4 rims",
+			Price:    200,
+		},
+		pack.Size{
+			ID:       "R10",
+			Capacity: 10,
+			Label:    "10 rims",
+			Price:    450,
+		},
+	})
+	memRepo.Save(ctx, rims)
+	memRepo.SaveBundle(ctx, pack.Bundle{
+		ID:       "starter",
+		Label:    "Starter set",
+		Price:    900,
+		Contents: pack.BundleContents{{SKU: "tires", Quantity: pack.Pieces(23)}, {SKU: "rims", Quantity: pack.Pieces(4)}},
+	})
 
-	allocSrv := allocation.NewService(memRepo, dpAlgo)
+	allocSrv := allocation.NewService(memRepo, registry, algorithms.Budget{
+		MaxCells:    cfg.AllocationMaxCells,
+		MaxDuration: cfg.AllocationMaxDuration,
+	})
+	if cfg.FastPath {
+		err := allocSrv.SetFastPath("greedy", algorithms.Gap{
+			Overfill: cfg.FastPathOverfillGap,
+			Packs:    cfg.FastPathPacksGap,
+			Cost:     cfg.FastPathCostGap,
+		})
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "setting fast path: %v", err)
+			return
+		}
+	}
+	if cfg.Strict {
+		allocSrv.SetStrict(cfg.StrictOptimalUpTo)
+	}
+	if cfg.ShadowAlgorithm != "" {
+		err := allocSrv.SetShadow(cfg.ShadowAlgorithm, log, allocation.ShadowConfig{
+			Concurrency: cfg.ShadowConcurrency,
+			Keep:        cfg.ShadowKeep,
+		})
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "setting shadow algorithm: %v", err)
+			return
+		}
+	}
+	allocSrv.SetShipmentLimits(algorithms.ShipmentLimits{
+		MaxWeight: cfg.ShipmentMaxWeight,
+		MaxVolume: cfg.ShipmentMaxVolume,
+	})
 	allocHandler := handlers.NewAllocationHandler(allocSrv)
+	shadowHandler := handlers.NewShadowHandler(allocSrv, render)
 
 	invSrv := inventory.NewService(memRepo)
 	invHandlers := handlers.NewInventoryHandler(invSrv, allocSrv, render, dec)
 
+	orderHandler := handlers.NewOrderHandler(invSrv, allocSrv, render, dec)
+	bundleHandler := handlers.NewBundleHandler(invSrv, render, dec)
+
 	idxHandler := handlers.NewIndexHandler(render)
 
-	registerRoutes(router, idxHandler, allocHandler, invHandlers)
+	registerRoutes(router, idxHandler, allocHandler, invHandlers, orderHandler, bundleHandler, shadowHandler)
 	log.Info("Routes Registered")
 
 	loggedRouter := gorillaHandlers.CustomLoggingHandler(
@@ -150,6 +257,9 @@ func registerRoutes(
 	handler *handlers.IndexHandler,
 	allocHandler *handlers.AllocationHandler,
 	invHandlers *handlers.InventoryHandler,
+	orderHandler *handlers.OrderHandler,
+	bundleHandler *handlers.BundleHandler,
+	shadowHandler *handlers.ShadowHandler,
 ) {
 	routes := []struct {
 		path    string
@@ -162,12 +272,32 @@ func registerRoutes(
 			methods: []string{"GET"},
 			h:       allocHandler.HandleAllocate,
 		},
+		{
+			path:    "/api/allocate/batch",
+			methods: []string{"POST"},
+			h:       allocHandler.HandleAllocateBatch,
+		},
+		{
+			path:    "/api/allocate/order",
+			methods: []string{"POST"},
+			h:       allocHandler.HandleAllocateOrder,
+		},
+		{
+			path:    "/api/reallocate",
+			methods: []string{"POST"},
+			h:       allocHandler.HandleReallocate,
+		},
 
 		{
 			path:    "/inventory/create",
 			methods: []string{"GET", "POST"},
 			h:       invHandlers.HandleCreate,
 		},
+		{
+			path:    "/inventory/{sku}/locations",
+			methods: []string{"POST"},
+			h:       invHandlers.HandleLocations,
+		},
 		{
 			path:    "/inventory/{sku}/update",
 			methods: []string{"POST"},
@@ -178,11 +308,36 @@ func registerRoutes(
 			methods: []string{"POST"},
 			h:       invHandlers.HandleDelete,
 		},
+		{
+			path:    "/inventory/{sku}/feasibility",
+			methods: []string{"GET"},
+			h:       invHandlers.HandleFeasibility,
+		},
 		{
 			path:    "/inventory/{sku}",
 			methods: []string{"GET", "POST"},
 			h:       invHandlers.HandleGet,
 		},
+		{
+			path:    "/order",
+			methods: []string{"GET", "POST"},
+			h:       orderHandler.HandleOrder,
+		},
+		{
+			path:    "/bundles/{id}/delete",
+			methods: []string{"POST"},
+			h:       bundleHandler.HandleDelete,
+		},
+		{
+			path:    "/bundles",
+			methods: []string{"GET", "POST"},
+			h:       bundleHandler.HandleBundles,
+		},
+		{
+			path:    "/shadow",
+			methods: []string{"GET"},
+			h:       shadowHandler.HandleShadow,
+		},
 		{
 			path:    "/inventory",
 			methods: []string{"GET"},
@@ -195,7 +350,8 @@ func registerRoutes(
 		},
 	}
 
+	// Note: paths nest, so they are matched whole, /api/allocate must not also answer /api/allocate/batch.
 	for _, r := range routes {
-		router.PathPrefix(r.path).Methods(r.methods...).Handler(r.h)
+		router.Path(r.path).Methods(r.methods...).Handler(r.h)
 	}
 }
@@ -1,11 +1,108 @@
 package algorithms
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
 	"github.com/IAmRadek/packing/internal/domain/pack"
 )
 
+// ErrInsufficientStock is returned when the packs on hand cannot cover the demand.
+var ErrInsufficientStock = errors.New("insufficient stock to cover demand")
+
+// ErrInfeasible is returned when no allocation satisfies the minimum quantities and multiples of the sizes.
+var ErrInfeasible = errors.New("no allocation satisfies the pack constraints")
+
+// ErrDemandTooLarge is returned when an allocator would need memory growing with the demand past what it supports,
+// whatever the budget.
+var ErrDemandTooLarge = errors.New("demand too large for the allocator")
+
+// Infeasible explains why no allocation of sizes covers the demand.
+func Infeasible(sizes pack.Sizes, demand int64, opts Options) error {
+	if opts.Bounded {
+		total := int64(0)
+		for _, s := range sizes {
+			hi, units := bits.Mul64(uint64(s.Capacity), uint64(s.Stock))
+			total += int64(units)
+			if hi != 0 || units > math.MaxInt64 || total < 0 {
+				return ErrInfeasible
+			}
+		}
+		if total < demand {
+			return ErrInsufficientStock
+		}
+	}
+	return ErrInfeasible
+}
+
+// Objective selects what an allocation optimizes for.
+type Objective string
+
+const (
+	// MinOverfill picks the smallest overfill, then the fewest packs.
+	MinOverfill Objective = "min_overfill"
+	// MinCost picks the lowest total price, then the smallest overfill, then the fewest packs.
+	MinCost Objective = "min_cost"
+)
+
+// ParseObjective validates an objective name, an empty name selects MinOverfill.
+func ParseObjective(name string) (Objective, error) {
+	switch Objective(name) {
+	case "", MinOverfill:
+		return MinOverfill, nil
+	case MinCost:
+		return MinCost, nil
+	default:
+		return "", fmt.Errorf("unknown objective: %q", name)
+	}
+}
+
+// Options tune a single allocation.
+type Options struct {
+	// Bounded never allocates more packs of a size than its Stock.
+	Bounded bool
+	// Objective defaults to MinOverfill.
+	Objective Objective
+	// Budget caps the resources spent on the allocation. Allocators whose tables grow with the demand, such
+	// as dp outside its unconstrained path, rely on it to bound their memory below ErrDemandTooLarge.
+	Budget Budget
+	// Meter, when set, is the meter of a larger search the allocation is a step of. The allocation counts its
+	// cells against it and stops once its time runs out, in place of Budget. Steps sharing a meter must run one
+	// after another, never concurrently.
+	Meter *Meter
+	// TieBreak picks between allocations that are equally good for the objective.
+	TieBreak pack.TieBreak
+	// MaxOverfill rejects allocations overshooting the demand by more than it allows.
+	MaxOverfill Tolerance
+}
+
 // Allocator defines interface for different algorithms.
 type Allocator interface {
 	// Allocate returns a map[PackID]PacksUsed to cover demand units, or error.
-	Allocate(sizes pack.Sizes, demand int64) (map[pack.ID]pack.Quantity, error)
+	// It stops early with ctx.Err() or a *BudgetError once ctx is done or opts.Budget runs out.
+	// It returns a *ToleranceError when every allocation overshoots opts.MaxOverfill.
+	Allocate(ctx context.Context, sizes pack.Sizes, demand int64, opts Options) (map[pack.ID]pack.Quantity, error)
+}
+
+// ExactAllocator is implemented by allocators whose allocations are optimal for their options, ranked by Rank.
+type ExactAllocator interface {
+	Allocator
+	Exact() bool
+}
+
+// BatchResult is the allocation of one demand of a batch, Err is set instead when that demand
+// cannot be allocated.
+type BatchResult struct {
+	Packs map[pack.ID]pack.Quantity
+	Err   error
+}
+
+// BatchAllocator is implemented by allocators that share work between many demands for the same sizes.
+type BatchAllocator interface {
+	// AllocateBatch returns one result per demand, matched by index.
+	// The returned error is reserved for failures of the whole batch, such as running out of budget.
+	AllocateBatch(ctx context.Context, sizes pack.Sizes, demands []int64, opts Options) ([]BatchResult, error)
 }
@@ -0,0 +1,346 @@
+package bnb
+
+import (
+	"context"
+	"math"
+	"math/bits"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Allocator searches pack counts depth first, the most preferred size first and larger counts first.
+// Branches whose bounds cannot beat the best allocation found so far are pruned. It settles ties the
+// same way as dp.Allocator, so both return identical allocations for identical options.
+type Allocator struct{}
+
+// Exact reports that every allocation is optimal for its options.
+func (a Allocator) Exact() bool {
+	return true
+}
+
+func (a Allocator) Allocate(ctx context.Context, sizes pack.Sizes, demand int64, opts algorithms.Options) (map[pack.ID]pack.Quantity, error) {
+	m := algorithms.MeterFor(ctx, opts)
+	order := opts.TieBreak.Order(sizes)
+	items := newItems(order, opts)
+
+	// Note: without stock, constraints or prices an optimal allocation never holds as many of the smaller
+	// packs as the largest one has units (in gcd steps), otherwise some of them could be swapped for fewer
+	// of the largest packs. The demand above that threshold is covered with the largest pack up front.
+	var largest pack.Size
+	bulk := int64(0)
+	if !opts.Bounded && opts.Objective != algorithms.MinCost && !sizes.Constrained() {
+		largest, bulk = reduce(sizes, demand)
+	}
+
+	s := search{
+		m:         m,
+		items:     items,
+		bounds:    newBounds(items),
+		demand:    demand - bulk*largest.Capacity,
+		objective: opts.Objective,
+		fewest:    opts.TieBreak.Rule == pack.PreferFewerSizes,
+		allowed:   opts.MaxOverfill.Allowed(demand),
+		counts:    make([]int64, len(items)),
+	}
+
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	if s.best == nil {
+		return nil, s.explain(sizes, demand, opts)
+	}
+
+	out := make(map[pack.ID]pack.Quantity)
+	for i, k := range s.best {
+		if k > 0 {
+			out[items[i].id] = pack.Quantity(k * items[i].step)
+		}
+	}
+	if bulk > 0 {
+		out[largest.ID] += pack.Quantity(bulk)
+	}
+
+	return out, nil
+}
+
+// explain tells an allocation out of tolerance apart from one that does not exist at all.
+func (s *search) explain(sizes pack.Sizes, demand int64, opts algorithms.Options) error {
+	if s.allowed < 0 {
+		return algorithms.Infeasible(sizes, demand, opts)
+	}
+
+	closest := *s
+	closest.objective = algorithms.MinOverfill
+	closest.fewest = false
+	closest.allowed = -1
+	closest.best = nil
+	if err := closest.run(); err != nil {
+		return err
+	}
+	if closest.best == nil {
+		return algorithms.Infeasible(sizes, demand, opts)
+	}
+
+	return &algorithms.ToleranceError{Allowed: s.allowed, Closest: closest.bestKey[1]}
+}
+
+// item is a pack size as seen by the search, taken in steps of Size.Multiple packs.
+type item struct {
+	id       pack.ID
+	capacity int64 // units of a single pack
+	units    int64 // units of one step
+	step     int64 // packs per step
+	least    int64 // fewest steps when used at all
+	limit    int64 // most steps, negative means unlimited
+	cost     int64 // per step
+	must     bool
+}
+
+func newItems(sizes pack.Sizes, opts algorithms.Options) []item {
+	out := make([]item, 0, len(sizes))
+	for _, s := range sizes {
+		step := max(1, int64(s.Multiple))
+		limit := int64(-1)
+		if opts.Bounded {
+			limit = int64(s.Stock) / step
+		}
+		cost := int64(0)
+		if opts.Objective == algorithms.MinCost {
+			cost = s.Price * step
+		}
+		out = append(out, item{
+			id:       s.ID,
+			capacity: s.Capacity,
+			units:    s.Capacity * step,
+			step:     step,
+			least:    max(1, ceilDiv(int64(s.MinQuantity), step)),
+			limit:    limit,
+			cost:     cost,
+			must:     s.Required,
+		})
+	}
+	return out
+}
+
+// bound summarizes items[i:] for the lower bounds of a search node.
+type bound struct {
+	gcd       int64 // of the step units, zero when no items are left
+	capacity  int64 // largest pack
+	total     int64 // most units the items can cover
+	mustUnits int64
+	mustPacks int64
+	mustCost  int64
+	mustCount int64
+	// ratioCost per ratioUnits is the cheapest price of a unit.
+	ratioCost  int64
+	ratioUnits int64
+}
+
+func newBounds(items []item) []bound {
+	out := make([]bound, len(items)+1)
+	for i := len(items) - 1; i >= 0; i-- {
+		it, b := items[i], out[i+1]
+		b.gcd = algorithms.GCD([]int64{b.gcd, it.units})
+		b.capacity = max(b.capacity, it.capacity)
+		if it.limit < 0 {
+			b.total = math.MaxInt64
+		} else {
+			b.total = addSat(b.total, mulSat(it.units, it.limit))
+		}
+		if it.must {
+			b.mustUnits = addSat(b.mustUnits, mulSat(it.least, it.units))
+			b.mustPacks = addSat(b.mustPacks, mulSat(it.least, it.step))
+			b.mustCost = addSat(b.mustCost, mulSat(it.least, it.cost))
+			b.mustCount++
+		}
+		if b.ratioUnits == 0 || mulSat(it.cost, b.ratioUnits) < mulSat(b.ratioCost, it.units) {
+			b.ratioCost, b.ratioUnits = it.cost, it.units
+		}
+		out[i] = b
+	}
+	return out
+}
+
+// key orders complete allocations by cost, overfill, packs and distinct sizes, smaller is better.
+type key [4]int64
+
+func (k key) less(o key) bool {
+	for i := range k {
+		if k[i] != o[i] {
+			return k[i] < o[i]
+		}
+	}
+	return false
+}
+
+type search struct {
+	m      *algorithms.Meter
+	items  []item
+	bounds []bound
+	// demand left once the packs taken up front are subtracted, the overfill is the same for both.
+	demand    int64
+	objective algorithms.Objective
+	fewest    bool
+	allowed   int64
+
+	counts  []int64
+	best    []int64
+	bestKey key
+}
+
+func (s *search) run() error {
+	return s.visit(0, 0, 0, 0, 0)
+}
+
+// key builds the key of a node, complete or not, from its measures.
+func (s *search) key(units, packs, cost, distinct int64) key {
+	k := key{0, units - s.demand, packs, 0}
+	if s.objective == algorithms.MinCost {
+		k[0] = cost
+	}
+	if s.fewest {
+		k[3] = distinct
+	}
+	return k
+}
+
+// visit branches on the count of items[i] after items[:i] covered units in packs for cost.
+func (s *search) visit(i int, units, packs, cost, distinct int64) error {
+	if err := s.m.Tick(); err != nil {
+		return err
+	}
+
+	rest := s.demand - units
+	b := s.bounds[i]
+	if b.total < rest {
+		return nil
+	}
+
+	// Note: a lower bound for every allocation below this node. The remaining items add a multiple of their
+	// gcd covering both the rest of the demand and the sizes that must be used.
+	need := max(rest, b.mustUnits, 0)
+	if b.gcd == 0 {
+		if need > 0 {
+			return nil
+		}
+	} else {
+		need = mulSat(ceilDiv(need, b.gcd), b.gcd)
+	}
+	free := max(rest-b.mustUnits, 0)
+	low := s.key(
+		addSat(units, need),
+		addSat(packs, addSat(b.mustPacks, ceilDiv(free, max(b.capacity, 1)))),
+		addSat(cost, addSat(b.mustCost, costBound(free, b.ratioCost, b.ratioUnits))),
+		distinct+b.mustCount,
+	)
+	if s.allowed >= 0 && low[1] > s.allowed {
+		return nil
+	}
+	if s.best != nil && !low.less(s.bestKey) {
+		return nil
+	}
+
+	if i == len(s.items) {
+		s.best = append(s.best[:0], s.counts...)
+		s.bestKey = low
+		return nil
+	}
+
+	it := s.items[i]
+	top := int64(0)
+	if rest > 0 {
+		top = max(ceilDiv(rest, it.units), it.least)
+	} else if it.must {
+		top = it.least
+	}
+	if it.limit >= 0 {
+		top = min(top, it.limit)
+	}
+
+	for k := top; k >= 0; k-- {
+		if k > 0 && k < it.least {
+			k = 0
+		}
+		if k == 0 && it.must {
+			break
+		}
+
+		used := int64(0)
+		if k > 0 {
+			used = 1
+		}
+		s.counts[i] = k
+		err := s.visit(i+1, units+k*it.units, packs+k*it.step, cost+k*it.cost, distinct+used)
+		s.counts[i] = 0
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// costBound is the least a number of units can cost at ratioCost per ratioUnits, rounded up.
+func costBound(units, ratioCost, ratioUnits int64) int64 {
+	if units == 0 || ratioCost == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(units), uint64(ratioCost))
+	if hi >= uint64(ratioUnits) {
+		return math.MaxInt64
+	}
+	q, r := bits.Div64(hi, lo, uint64(ratioUnits))
+	if r != 0 {
+		q++
+	}
+	if q > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(q)
+}
+
+// reduce returns the largest size and how many of its packs any optimal allocation of demand holds.
+func reduce(sizes pack.Sizes, demand int64) (pack.Size, int64) {
+	largest, second := sizes[0], int64(0)
+	for _, s := range sizes[1:] {
+		if s.Capacity > largest.Capacity {
+			largest = s
+		}
+	}
+	for _, s := range sizes {
+		if s.Capacity < largest.Capacity {
+			second = max(second, s.Capacity)
+		}
+	}
+
+	g := algorithms.GCD(sizes.Capacities())
+	scaled, top := ceilDiv(demand, g), largest.Capacity/g
+	threshold := mulSat(top-1, second/g)
+	if scaled-threshold < top {
+		return largest, 0
+	}
+	return largest, (scaled - threshold) / top
+}
+
+func ceilDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 {
+		q++
+	}
+	return q
+}
+
+func mulSat(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+func addSat(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
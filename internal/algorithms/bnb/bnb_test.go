@@ -0,0 +1,129 @@
+package bnb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/algorithms/dp"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    pack.Sizes
+		quantity int64
+		exp      map[pack.ID]pack.Quantity
+	}{
+		{
+			name:     "edge_case",
+			sizes:    pack.Sizes{{ID: "23", Capacity: 23}, {ID: "31", Capacity: 31}, {ID: "53", Capacity: 53}},
+			quantity: 500_000,
+			exp:      map[pack.ID]pack.Quantity{"23": 2, "31": 7, "53": 9429},
+		},
+		{
+			name:     "tricky",
+			sizes:    pack.Sizes{{ID: "250", Capacity: 250}, {ID: "500", Capacity: 500}, {ID: "1000", Capacity: 1000}},
+			quantity: 251,
+			exp:      map[pack.ID]pack.Quantity{"500": 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Allocator{}.Allocate(context.Background(), tc.sizes, tc.quantity, algorithms.Options{})
+			if err != nil {
+				t.Fatalf("Allocate() error = %v", err)
+			}
+			if !maps.Equal(got, tc.exp) {
+				t.Errorf("Allocate() = %v, want %v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestAllocate_SameAsDP(t *testing.T) {
+	base := pack.Sizes{
+		{ID: "S", Capacity: 23, Stock: 30, Price: 100},
+		{ID: "L", Capacity: 31, Stock: 20, Price: 120},
+		{ID: "XL", Capacity: 53, Stock: 8, Price: 300},
+	}
+	constrained := pack.Sizes{
+		{ID: "A", Capacity: 4, MinQuantity: 2, Stock: 9, Price: 5},
+		{ID: "B", Capacity: 6, Multiple: 3, Stock: 12, Price: 6},
+		{ID: "C", Capacity: 9, MinQuantity: 2, Multiple: 2, Stock: 8, Price: 10},
+	}
+	required, err := constrained.Requiring([]pack.ID{"B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fewer, err := pack.NewTieBreak(string(pack.PreferFewerSizes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priority, err := pack.NewTieBreak(string(pack.PreferPriority), []pack.ID{"S", "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		sizes pack.Sizes
+		opts  algorithms.Options
+	}{
+		{name: "unbounded", sizes: base},
+		{name: "bounded", sizes: base, opts: algorithms.Options{Bounded: true}},
+		{name: "cheapest", sizes: base, opts: algorithms.Options{Objective: algorithms.MinCost}},
+		{name: "cheapest bounded", sizes: base, opts: algorithms.Options{Objective: algorithms.MinCost, Bounded: true}},
+		{name: "fewer sizes", sizes: base, opts: algorithms.Options{TieBreak: fewer}},
+		{name: "priority", sizes: base, opts: algorithms.Options{TieBreak: priority}},
+		{name: "exact fill", sizes: base, opts: algorithms.Options{MaxOverfill: algorithms.Tolerance{Limited: true}}},
+		{name: "cheapest within 5%", sizes: base, opts: algorithms.Options{
+			Objective:   algorithms.MinCost,
+			MaxOverfill: algorithms.Tolerance{Limited: true, BasisPoints: 500},
+		}},
+		{name: "constrained", sizes: constrained},
+		{name: "constrained bounded", sizes: constrained, opts: algorithms.Options{Bounded: true}},
+		{name: "constrained cheapest", sizes: constrained, opts: algorithms.Options{Objective: algorithms.MinCost, TieBreak: priority}},
+		{name: "required", sizes: required, opts: algorithms.Options{Bounded: true, TieBreak: fewer}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for demand := int64(1); demand <= 1_200; demand++ {
+				want, wantErr := dp.Allocator{}.Allocate(context.Background(), tc.sizes, demand, tc.opts)
+				got, err := Allocator{}.Allocate(context.Background(), tc.sizes, demand, tc.opts)
+				if fmt.Sprint(err) != fmt.Sprint(wantErr) {
+					t.Fatalf("demand %d: Allocate() error = %v, want %v", demand, err, wantErr)
+				}
+				if !maps.Equal(got, want) {
+					t.Fatalf("demand %d: Allocate() = %v, want %v", demand, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_Budget(t *testing.T) {
+	sizes := pack.Sizes{{ID: "S", Capacity: 23}, {ID: "L", Capacity: 31}, {ID: "XL", Capacity: 53}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Allocator{}.Allocate(ctx, sizes, 1_000_000, algorithms.Options{Objective: algorithms.MinCost})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Allocate() error = %v, want %v", err, context.Canceled)
+	}
+
+	_, err = Allocator{}.Allocate(context.Background(), sizes, 1_000_000_000, algorithms.Options{
+		Objective: algorithms.MinCost,
+		Budget:    algorithms.Budget{MaxDuration: time.Millisecond},
+	})
+	if !errors.Is(err, algorithms.ErrBudgetExceeded) {
+		t.Errorf("Allocate() error = %v, want %v", err, algorithms.ErrBudgetExceeded)
+	}
+}
@@ -0,0 +1,104 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExceeded is matched by every BudgetError.
+var ErrBudgetExceeded = errors.New("allocation budget exceeded")
+
+// Resource names a limited resource of a Budget.
+type Resource string
+
+const (
+	Cells    Resource = "cells"
+	WallTime Resource = "wall_time"
+)
+
+// Budget caps the resources of a single allocation, zero values mean no limit.
+type Budget struct {
+	// MaxCells limits the number of table cells an allocator may hold.
+	MaxCells int64
+	// MaxDuration limits the wall time of an allocation.
+	MaxDuration time.Duration
+}
+
+// BudgetError reports which resource of a Budget ran out.
+type BudgetError struct {
+	Resource Resource
+	Limit    string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %s", ErrBudgetExceeded, e.Resource, e.Limit)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// checkEvery is how many Tick calls pass between looking at the clock and the context.
+const checkEvery = 1 << 12
+
+// Meter tracks the budget of a single allocation. It is not safe for concurrent use.
+type Meter struct {
+	ctx      context.Context
+	budget   Budget
+	deadline time.Time
+	cells    int64
+	ticks    int
+}
+
+// NewMeter starts tracking budget.
+func NewMeter(ctx context.Context, budget Budget) *Meter {
+	m := &Meter{
+		ctx:    ctx,
+		budget: budget,
+	}
+	if budget.MaxDuration > 0 {
+		m.deadline = time.Now().Add(budget.MaxDuration)
+	}
+	return m
+}
+
+// Reserve accounts for n more table cells.
+func (m *Meter) Reserve(n int64) error {
+	m.cells += n
+	if m.budget.MaxCells > 0 && (m.cells > m.budget.MaxCells || m.cells < 0) {
+		return &BudgetError{Resource: Cells, Limit: fmt.Sprint(m.budget.MaxCells)}
+	}
+	return nil
+}
+
+// Tick is meant to be called from hot loops, it checks the deadline every so often.
+func (m *Meter) Tick() error {
+	m.ticks++
+	if m.ticks < checkEvery {
+		return nil
+	}
+	m.ticks = 0
+	return m.Check()
+}
+
+// Check returns an error once the context is done or the time budget ran out.
+func (m *Meter) Check() error {
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	if !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		return &BudgetError{Resource: WallTime, Limit: m.budget.MaxDuration.String()}
+	}
+	return nil
+}
+
+// MeterFor returns the meter an allocation with opts draws on: opts.Meter when it is set, otherwise a new meter
+// of opts.Budget.
+func MeterFor(ctx context.Context, opts Options) *Meter {
+	if opts.Meter != nil {
+		return opts.Meter
+	}
+	return NewMeter(ctx, opts.Budget)
+}
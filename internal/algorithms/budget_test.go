@@ -0,0 +1,53 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMeterFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  Budget
+		outer   int64
+		inner   int64
+		wait    time.Duration
+		wantErr error
+	}{
+		{name: "within", budget: Budget{MaxCells: 10}, outer: 4, inner: 6},
+		{name: "cells add up", budget: Budget{MaxCells: 10}, outer: 6, inner: 6, wantErr: ErrBudgetExceeded},
+		{name: "time runs out", budget: Budget{MaxDuration: time.Millisecond}, wait: 5 * time.Millisecond, wantErr: ErrBudgetExceeded},
+		{name: "no limit", outer: 1 << 40, inner: 1 << 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMeter(context.Background(), tt.budget)
+			if err := m.Reserve(tt.outer); err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+			time.Sleep(tt.wait)
+
+			inner := MeterFor(context.Background(), Options{Budget: Budget{MaxCells: 1}, Meter: m})
+			err := inner.Reserve(tt.inner)
+			if err == nil {
+				err = inner.Check()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("inner meter error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Note: copies of the options only share a meter they were given explicitly.
+	opts := Options{Budget: Budget{MaxCells: 10}}
+	first, second := MeterFor(context.Background(), opts), MeterFor(context.Background(), opts)
+	if err := first.Reserve(6); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := second.Reserve(6); err != nil {
+		t.Errorf("Reserve() of a separate meter error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,294 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Dimension is one SKU of a multi-SKU demand along with the single-SKU sizes allocating it, by their own
+// allocator and options.
+type Dimension struct {
+	Demand    int64
+	Sizes     pack.Sizes
+	Allocator Allocator
+	Options   Options
+	// Step is how many items make one unit of the measure of the dimension, such as 1000 for kilograms kept
+	// to the gram. Zero counts as one.
+	Step int64
+}
+
+// BundleSize is a pack holding fixed quantities of several SKUs.
+type BundleSize struct {
+	ID    pack.ID
+	Price int64
+	// Contents counts the items of every dimension one bundle holds, matched to the dimensions by index.
+	Contents []int64
+}
+
+// Bundled is an allocation of a multi-SKU demand.
+type Bundled struct {
+	Bundles map[pack.ID]pack.Quantity
+	// Singles holds the single-SKU packs of every dimension, matched by index.
+	Singles []map[pack.ID]pack.Quantity
+	// Proven is set when no allocation ranks better, every allocator is exact and the search finished in budget.
+	Proven bool
+}
+
+// AllocateBundled covers the demand of every dimension with bundles and single-SKU sizes. For MinOverfill it
+// ranks allocations by the overfill of all dimensions added up, then packs and cost, for MinCost by cost first.
+// Overfill is added up in units of the measures of the dimensions rather than in items, so a dimension kept to
+// the gram does not weigh a thousand times one kept to the kilogram. Every dimension keeps to the tolerance of
+// its options.
+//
+// Bundle counts are searched depth first, each up to the count covering alone every demand the bundle holds
+// since more would only add overfill. What the bundles leave of a demand is allocated by the allocator of the
+// dimension, once per distinct remainder. Each count tried and each remainder kept is a cell of the budget, the
+// allocations of the dimensions draw on it as well in place of the budgets of their options. Only the objective
+// and the budget or meter of opts apply.
+func AllocateBundled(ctx context.Context, dims []Dimension, bundles []BundleSize, opts Options) (Bundled, error) {
+	for i, d := range dims {
+		if d.Step < 0 {
+			return Bundled{}, fmt.Errorf("dimension %d has a negative step", i)
+		}
+	}
+	for _, b := range bundles {
+		if len(b.Contents) != len(dims) {
+			return Bundled{}, fmt.Errorf("bundle %s holds %d dimensions, want %d", b.ID, len(b.Contents), len(dims))
+		}
+		if slices.ContainsFunc(b.Contents, func(n int64) bool { return n < 0 }) {
+			return Bundled{}, fmt.Errorf("bundle %s holds a negative quantity", b.ID)
+		}
+	}
+
+	s := bundler{
+		m:         MeterFor(ctx, opts),
+		dims:      dims,
+		bundles:   bundles,
+		objective: opts.Objective,
+		counts:    make([]int64, len(bundles)),
+		covered:   make([]int64, len(dims)),
+		weights:   make([]int64, len(dims)),
+		memo:      make([]map[int64]single, len(dims)),
+		last:      ErrInfeasible,
+		exact:     true,
+	}
+	// Note: the finest step is a multiple of every step, so the overfill of each dimension stays whole in it.
+	finest := int64(1)
+	for _, d := range dims {
+		step := max(d.Step, 1)
+		finest = mulSat(finest/GCD([]int64{finest, step}), step)
+	}
+	for i, d := range dims {
+		s.memo[i] = make(map[int64]single)
+		s.weights[i] = finest / max(d.Step, 1)
+		if e, ok := d.Allocator.(ExactAllocator); !ok || !e.Exact() {
+			s.exact = false
+		}
+	}
+
+	err := s.visit(0, 0)
+	switch {
+	case err != nil && !(s.found && errors.Is(err, ErrBudgetExceeded)):
+		return Bundled{}, err
+	case !s.found:
+		return Bundled{}, s.last
+	}
+
+	out := Bundled{
+		Bundles: make(map[pack.ID]pack.Quantity),
+		Singles: s.best,
+		Proven:  s.exact && err == nil,
+	}
+	for k, n := range s.bestCounts {
+		if n > 0 {
+			out.Bundles[bundles[k].ID] = pack.Quantity(n)
+		}
+	}
+	return out, nil
+}
+
+// single is the allocation of what bundles leave of the demand of one dimension, with its cost, items, packs
+// and distinct sizes.
+type single struct {
+	dist     map[pack.ID]pack.Quantity
+	measures [4]int64
+	err      error
+}
+
+type bundler struct {
+	m    *Meter
+	dims []Dimension
+	// weights turn the overfill of every dimension into steps of the finest measure.
+	weights   []int64
+	bundles   []BundleSize
+	objective Objective
+	exact     bool
+
+	// counts are the bundles taken so far, covered the items they hold of every dimension.
+	counts  []int64
+	covered []int64
+	memo    []map[int64]single
+
+	found      bool
+	best       []map[pack.ID]pack.Quantity
+	bestCounts []int64
+	bestRank   Rank
+	last       error
+}
+
+// visit branches on the count of bundles[k] after the ones before it cost cost.
+func (s *bundler) visit(k int, cost int64) error {
+	if err := s.m.Reserve(1); err != nil {
+		return err
+	}
+	if err := s.m.Tick(); err != nil {
+		return err
+	}
+
+	// Note: the overfill forced by the bundles taken only grows with more of them.
+	forced := int64(0)
+	for i, d := range s.dims {
+		forced = addSat(forced, mulSat(max(s.covered[i]-d.Demand, 0), s.weights[i]))
+	}
+	if s.found {
+		low := Rank{0, forced, 0, 0}
+		if s.objective == MinCost {
+			low = Rank{cost, 0, 0, 0}
+		}
+		if s.bestRank.Less(low) {
+			return nil
+		}
+	}
+
+	if k == len(s.bundles) {
+		return s.leaf(cost)
+	}
+
+	b := s.bundles[k]
+	top := int64(0)
+	for i, n := range b.Contents {
+		if n > 0 {
+			top = max(top, ceilDiv(max(s.dims[i].Demand, 0), n))
+		}
+	}
+
+	for n := int64(0); n <= top; n++ {
+		s.counts[k] = n
+		for i, c := range b.Contents {
+			s.covered[i] = addSat(s.covered[i], mulSat(n, c))
+		}
+		err := s.visit(k+1, addSat(cost, mulSat(n, b.Price)))
+		for i, c := range b.Contents {
+			s.covered[i] -= mulSat(n, c)
+		}
+		s.counts[k] = 0
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leaf allocates the rest of every demand with single-SKU sizes and keeps the allocation when it ranks best.
+func (s *bundler) leaf(cost int64) error {
+	var total [4]int64
+	total[0] = cost
+	for _, n := range s.counts {
+		total[2] = addSat(total[2], n)
+	}
+
+	singles := make([]map[pack.ID]pack.Quantity, len(s.dims))
+	for i, d := range s.dims {
+		one, err := s.single(i, max(d.Demand-s.covered[i], 0))
+		if err != nil {
+			return err
+		}
+		if one.err != nil {
+			s.fail(one.err)
+			return nil
+		}
+
+		overfill := s.covered[i] + one.measures[1] - d.Demand
+		if !d.Options.MaxOverfill.Accepts(d.Demand, overfill) {
+			s.fail(&ToleranceError{Allowed: d.Options.MaxOverfill.Allowed(d.Demand), Closest: overfill})
+			return nil
+		}
+		singles[i] = one.dist
+		total[0] = addSat(total[0], one.measures[0])
+		total[1] = addSat(total[1], mulSat(overfill, s.weights[i]))
+		total[2] = addSat(total[2], one.measures[2])
+	}
+
+	rank := Rank{0, total[1], total[2], total[0]}
+	if s.objective == MinCost {
+		rank = Rank{total[0], total[1], total[2], 0}
+	}
+	if !s.found || rank.Less(s.bestRank) {
+		s.found = true
+		s.best = singles
+		s.bestCounts = slices.Clone(s.counts)
+		s.bestRank = rank
+	}
+	return nil
+}
+
+// fail keeps the reason an allocation was ruled out when none is found. Missing the tolerance says more than
+// finding nothing at all, so the closest miss is kept.
+func (s *bundler) fail(err error) {
+	var last, next *ToleranceError
+	switch {
+	case !errors.As(err, &next):
+		if !errors.As(s.last, &last) {
+			s.last = err
+		}
+	case !errors.As(s.last, &last) || next.Closest < last.Closest:
+		s.last = err
+	}
+}
+
+// single allocates rest items of dims[i], remembering the outcome for the next time the same rest comes up.
+// Allocations the dimension rules out are reported in the outcome, the error is kept for failures of the
+// whole search such as running out of budget.
+func (s *bundler) single(i int, rest int64) (single, error) {
+	if one, ok := s.memo[i][rest]; ok {
+		return one, nil
+	}
+
+	d := s.dims[i]
+	var one single
+	if rest == 0 {
+		one.dist = map[pack.ID]pack.Quantity{}
+	} else {
+		opts := d.Options
+		opts.Objective = s.objective
+		// Note: the tolerance applies to the whole demand of the dimension. Bundles leaving a rest add no
+		// overfill, so the rest may take all the demand allows.
+		if opts.MaxOverfill.Limited {
+			opts.MaxOverfill = Tolerance{Limited: true, Units: opts.MaxOverfill.Allowed(d.Demand)}
+		}
+		opts.Meter = s.m
+		dist, err := d.Allocator.Allocate(s.m.ctx, d.Sizes, rest, opts)
+		if err == nil {
+			err = s.m.Check()
+		}
+		switch {
+		case errors.Is(err, ErrInfeasible), errors.Is(err, ErrInsufficientStock), errors.Is(err, ErrOutOfTolerance):
+			one.err = err
+		case err != nil:
+			return single{}, err
+		default:
+			one.dist = dist
+			one.measures = measure(d.Sizes, dist)
+		}
+	}
+
+	if err := s.m.Reserve(1 + int64(len(one.dist))); err != nil {
+		return single{}, err
+	}
+	s.memo[i][rest] = one
+	return one, nil
+}
@@ -0,0 +1,161 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestAllocateBundled(t *testing.T) {
+	dims := func(a Allocator, opts Options, demands ...int64) []Dimension {
+		sizes := []pack.Sizes{
+			{{ID: "A5", Capacity: 5, Price: 10, Stock: 1}},
+			{{ID: "B3", Capacity: 3, Price: 9, Stock: 1}},
+		}
+		out := make([]Dimension, len(demands))
+		for i, d := range demands {
+			out[i] = Dimension{Demand: d, Sizes: sizes[i], Allocator: a, Options: opts}
+		}
+		return out
+	}
+	variety := func(price int64) []BundleSize {
+		return []BundleSize{{ID: "V", Price: price, Contents: []int64{4, 2}}}
+	}
+	exact := Options{MaxOverfill: Tolerance{Limited: true}}
+
+	tests := []struct {
+		name        string
+		dims        []Dimension
+		bundles     []BundleSize
+		objective   Objective
+		budget      Budget
+		wantBundles map[pack.ID]pack.Quantity
+		wantSingles []map[pack.ID]pack.Quantity
+		wantProof   bool
+		wantErr     error
+	}{
+		{
+			name:        "bundles fill exactly",
+			dims:        dims(exactBrute{}, Options{}, 8, 4),
+			bundles:     variety(40),
+			wantBundles: map[pack.ID]pack.Quantity{"V": 2},
+			wantSingles: []map[pack.ID]pack.Quantity{{}, {}},
+			wantProof:   true,
+		},
+		{
+			name:        "bundle and singles",
+			dims:        dims(exactBrute{}, Options{}, 13, 5),
+			bundles:     variety(12),
+			wantBundles: map[pack.ID]pack.Quantity{"V": 1},
+			wantSingles: []map[pack.ID]pack.Quantity{{"A5": 2}, {"B3": 1}},
+			wantProof:   true,
+		},
+		{
+			name:        "singles only",
+			dims:        dims(exactBrute{}, Options{}, 10, 6),
+			wantBundles: map[pack.ID]pack.Quantity{},
+			wantSingles: []map[pack.ID]pack.Quantity{{"A5": 2}, {"B3": 2}},
+			wantProof:   true,
+		},
+		{
+			name:        "cheapest",
+			dims:        dims(exactBrute{}, Options{}, 8, 4),
+			bundles:     variety(40),
+			objective:   MinCost,
+			wantBundles: map[pack.ID]pack.Quantity{},
+			wantSingles: []map[pack.ID]pack.Quantity{{"A5": 2}, {"B3": 2}},
+			wantProof:   true,
+		},
+		{
+			name:        "exact fill",
+			dims:        dims(exactBrute{}, exact, 9, 2),
+			bundles:     variety(12),
+			wantBundles: map[pack.ID]pack.Quantity{"V": 1},
+			wantSingles: []map[pack.ID]pack.Quantity{{"A5": 1}, {}},
+			wantProof:   true,
+		},
+		{
+			name:        "not exact",
+			dims:        dims(bruteAllocator{}, Options{}, 13, 5),
+			bundles:     variety(12),
+			wantBundles: map[pack.ID]pack.Quantity{"V": 1},
+			wantSingles: []map[pack.ID]pack.Quantity{{"A5": 2}, {"B3": 1}},
+		},
+		{
+			// Note: 500 steps of a kilogram kept to the gram are half a unit, less than the two pieces of the bundle.
+			name: "overfill in units of the measures",
+			dims: []Dimension{
+				{Demand: 1000, Sizes: pack.Sizes{{ID: "KG", Capacity: 1500}}, Allocator: exactBrute{}, Step: 1000},
+				{Demand: 1, Sizes: pack.Sizes{{ID: "PC", Capacity: 1}}, Allocator: exactBrute{}},
+			},
+			bundles:     []BundleSize{{ID: "V", Contents: []int64{1000, 3}}},
+			wantBundles: map[pack.ID]pack.Quantity{},
+			wantSingles: []map[pack.ID]pack.Quantity{{"KG": 1}, {"PC": 1}},
+			wantProof:   true,
+		},
+		{
+			name: "overfill in units of the same measure",
+			dims: []Dimension{
+				{Demand: 1000, Sizes: pack.Sizes{{ID: "KG", Capacity: 1500}}, Allocator: exactBrute{}, Step: 1000},
+				{Demand: 1000, Sizes: pack.Sizes{{ID: "G", Capacity: 1000}}, Allocator: exactBrute{}, Step: 1000},
+			},
+			bundles:     []BundleSize{{ID: "V", Contents: []int64{1000, 1003}}},
+			wantBundles: map[pack.ID]pack.Quantity{"V": 1},
+			wantSingles: []map[pack.ID]pack.Quantity{{}, {}},
+			wantProof:   true,
+		},
+		{name: "out of tolerance", dims: dims(exactBrute{}, exact, 1, 1), bundles: variety(12), wantErr: ErrOutOfTolerance},
+		{name: "infeasible", dims: dims(exactBrute{}, Options{Bounded: true}, 20, 1), wantErr: ErrInfeasible},
+		{
+			name:    "allocations share the budget",
+			dims:    dims(exactBrute{}, Options{Budget: Budget{MaxCells: 1 << 40}}, 10000, 10000),
+			bundles: variety(12),
+			budget:  Budget{MaxCells: 100},
+			wantErr: ErrBudgetExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AllocateBundled(context.Background(), tt.dims, tt.bundles, Options{Objective: tt.objective, Budget: tt.budget})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AllocateBundled() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !maps.Equal(got.Bundles, tt.wantBundles) || got.Proven != tt.wantProof {
+				t.Errorf("AllocateBundled() bundles = %v proven %v, want %v proven %v", got.Bundles, got.Proven, tt.wantBundles, tt.wantProof)
+			}
+			for i, want := range tt.wantSingles {
+				if !equalPacks(got.Singles[i], want) {
+					t.Errorf("AllocateBundled() singles of %d = %v, want %v", i, got.Singles[i], want)
+				}
+			}
+		})
+	}
+
+	_, err := AllocateBundled(context.Background(), dims(exactBrute{}, Options{}, 1, 1), []BundleSize{{ID: "V", Contents: []int64{1}}}, Options{})
+	if err == nil {
+		t.Error("AllocateBundled() accepted a bundle missing a dimension")
+	}
+}
+
+// equalPacks compares allocations ignoring sizes taken zero times.
+func equalPacks(got, want map[pack.ID]pack.Quantity) bool {
+	for id, q := range got {
+		if q != want[id] {
+			return false
+		}
+	}
+	for id, q := range want {
+		if q != got[id] {
+			return false
+		}
+	}
+	return true
+}
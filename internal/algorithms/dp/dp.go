@@ -1,108 +1,306 @@
+// Package dp allocates packs by dynamic programming over the amounts the packs can sum to.
+//
+// Only unconstrained allocations keep their memory independent of the demand: without stock, prices, a
+// fewer-sizes tie break or size constraints, all but a remainder bounded by the sizes is covered with the
+// largest pack up front. Every other allocation builds tables spanning the demand divided by the gcd of the
+// sizes, capped by the stock when it is tracked. Its memory grows with the demand and is bounded by
+// Options.Budget, while demands needing tables past 1<<24 targets fail with algorithms.ErrDemandTooLarge.
 package dp
 
 import (
+	"context"
 	"math"
+	"slices"
 
+	"github.com/IAmRadek/packing/internal/algorithms"
 	"github.com/IAmRadek/packing/internal/domain/pack"
 )
 
 type Allocator struct{}
 
-func (a Allocator) Allocate(sizes pack.Sizes, demand int64) (map[pack.ID]pack.Quantity, error) {
-	dist := Allocate(sizes.Capacities(), demand)
+// Exact reports that every allocation is optimal for its options.
+func (a Allocator) Exact() bool {
+	return true
+}
+
+func (a Allocator) Allocate(ctx context.Context, sizes pack.Sizes, demand int64, opts algorithms.Options) (map[pack.ID]pack.Quantity, error) {
+	out, err := a.AllocateBatch(ctx, sizes, []int64{demand}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out[0].Packs, out[0].Err
+}
+
+// AllocateBatch builds the tables once for the largest of the demands and reads every allocation off them.
+func (a Allocator) AllocateBatch(ctx context.Context, sizes pack.Sizes, demands []int64, opts algorithms.Options) ([]algorithms.BatchResult, error) {
+	m := algorithms.MeterFor(ctx, opts)
+	p := newPlan(sizes, opts)
 
-	out := make(map[pack.ID]pack.Quantity, len(dist))
-	for k, v := range dist {
-		s, _ := sizes.ByCapacity(k)
-		out[s.ID] = pack.Quantity(v)
+	dists := make([]map[int64]int64, len(demands))
+	errs := make([]error, len(demands))
+	if opts.Bounded || p.objective == algorithms.MinCost || p.fewest || sizes.Constrained() {
+		outcomes, err := allocate(m, items(sizes, opts), demands, p)
+		if err != nil {
+			return nil, err
+		}
+		for i, o := range outcomes {
+			dists[i], errs[i] = o.dist, o.err
+			if o.err == nil && o.dist == nil {
+				errs[i] = algorithms.Infeasible(sizes, demands[i], opts)
+			}
+		}
+	} else {
+		var err error
+		dists, err = distribute(m, sizes.Capacities(), demands, p.prefer)
+		if err != nil {
+			return nil, err
+		}
+		for i, dist := range dists {
+			if dist == nil {
+				errs[i] = algorithms.Infeasible(sizes, demands[i], opts)
+				continue
+			}
+			errs[i] = within(dist, demands[i], opts.MaxOverfill)
+		}
+	}
+
+	out := make([]algorithms.BatchResult, len(demands))
+	for i, dist := range dists {
+		if errs[i] != nil {
+			out[i].Err = errs[i]
+			continue
+		}
+		out[i].Packs = make(map[pack.ID]pack.Quantity, len(dist))
+		for k, v := range dist {
+			s, _ := sizes.ByCapacity(k)
+			out[i].Packs[s.ID] = pack.Quantity(v)
+		}
 	}
 
 	return out, nil
 }
 
-// Allocate tries to distribute `demand` into packs of `sizes`
+// within checks the overfill of dist against the tolerance.
+func within(dist map[int64]int64, demand int64, tolerance algorithms.Tolerance) error {
+	items := int64(0)
+	for s, k := range dist {
+		items = addSat(items, mulSat(s, k))
+	}
+	if overfill := items - demand; !tolerance.Accepts(demand, overfill) {
+		return &algorithms.ToleranceError{Allowed: tolerance.Allowed(demand), Closest: overfill}
+	}
+	return nil
+}
+
+// plan is what an allocation optimizes for and how it settles ties.
+type plan struct {
+	objective algorithms.Objective
+	// prefer lists capacities from the most to the least preferred, nil prefers larger ones.
+	prefer []int64
+	// fewest looks for the fewest distinct sizes before applying prefer.
+	fewest bool
+	// tolerance caps the overfill, the zero value accepts any.
+	tolerance algorithms.Tolerance
+}
+
+func newPlan(sizes pack.Sizes, opts algorithms.Options) plan {
+	return plan{
+		objective: opts.Objective,
+		prefer:    opts.TieBreak.Order(sizes).Capacities(),
+		fewest:    opts.TieBreak.Rule == pack.PreferFewerSizes,
+		tolerance: opts.MaxOverfill,
+	}
+}
+
+// items converts sizes into solver items, limited by stock when opts.Bounded is set.
+func items(sizes pack.Sizes, opts algorithms.Options) []item {
+	out := make([]item, 0, len(sizes))
+	for _, s := range sizes {
+		step := max([]int64{1, int64(s.Multiple)})
+		limit := int64(-1)
+		if opts.Bounded {
+			limit = int64(s.Stock) / step
+		}
+		out = append(out, item{
+			pack:  s.Capacity,
+			size:  s.Capacity * step,
+			step:  step,
+			least: max([]int64{1, ceilDiv(int64(s.MinQuantity), step)}),
+			limit: limit,
+			cost:  s.Price * step,
+			must:  s.Required,
+		})
+	}
+	return out
+}
+
+// Allocate tries to distribute `demand` into packs of `sizes`, preferring larger packs on ties.
 func Allocate(sizes []int64, demand int64) map[int64]int64 {
+	out, _ := distribute(unlimited(), sizes, []int64{demand}, nil)
+	return out[0]
+}
+
+// unlimited returns a meter that never runs out.
+func unlimited() *algorithms.Meter {
+	return algorithms.NewMeter(context.Background(), algorithms.Budget{})
+}
+
+// distribute covers every one of `demands` with packs of `sizes`, sharing a single table between them.
+func distribute(m *algorithms.Meter, sizes []int64, demands []int64, prefer []int64) ([]map[int64]int64, error) {
+	if prefer == nil {
+		prefer = slices.Clone(sizes)
+		slices.SortFunc(prefer, func(a, b int64) int {
+			return int(b - a)
+		})
+	}
+
 	// Note: find the greatest common divisor so we can shrink the search space.
-	g := gcd(sizes)
+	g := algorithms.GCD(sizes)
 	for i := range sizes {
 		sizes[i] /= g
 	}
-	demand = (demand + g - 1) / g // ceil so total ≥ original demand/g
+	reduced := make([]int64, len(prefer))
+	for i, s := range prefer {
+		reduced[i] = s / g
+	}
 
+	// Note: an optimal solution never holds maxS or more of the smaller packs, otherwise some of
+	// them would sum to a multiple of maxS and could be swapped for fewer of the largest packs.
+	// Everything above that threshold is therefore covered with the largest pack up front,
+	// which keeps the tables bounded by the sizes instead of the demand.
 	maxS := max(sizes)
-	limit := demand + maxS
-
-	// Note: the following section calculates reachable targets given the sizes.
-	reachable := make([]bool, limit+1)
-	reachable[0] = true
-	for _, s := range sizes {
-		for t := s; t <= limit; t++ {
-			reachable[t] = reachable[t] || reachable[t-s]
+	threshold := mulSat(maxS-1, second(sizes))
+	rest := make([]int64, len(demands))
+	bulk := make([]int64, len(demands))
+	top := int64(0)
+	for i, demand := range demands {
+		demand = ceilDiv(demand, g) // ceil so total ≥ original demand/g
+		if demand-threshold >= maxS {
+			bulk[i] = (demand - threshold) / maxS
+			demand -= bulk[i] * maxS
 		}
+		rest[i] = demand
+		top = max([]int64{top, demand})
 	}
 
-	// Note: searching for the first possible reachable target.
-	target := int64(-1)
-	for t := demand; t <= limit; t++ {
-		if reachable[t] {
-			target = t
-			break
+	packs, err := table(m, sizes, top+maxS)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]map[int64]int64, len(demands))
+	for i, demand := range rest {
+		res, err := walk(m, packs, demand, reduced)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			continue
 		}
+
+		dist := make(map[int64]int64, len(res))
+		for s, k := range res {
+			dist[s*g] = k // restore the original unit size from gcd
+		}
+		if bulk[i] > 0 {
+			dist[maxS*g] += bulk[i]
+		}
+		out[i] = dist
 	}
-	// Note: if not found, no solution is possible.
-	if target == -1 {
-		return nil
+
+	return out, nil
+}
+
+// exact distributes `demand` into packs of `sizes` with tables spanning the whole demand.
+// Both are expected to be already divided by their gcd, `prefer` lists the same sizes
+// from the most to the least preferred.
+func exact(m *algorithms.Meter, sizes []int64, demand int64, prefer []int64) (map[int64]int64, error) {
+	packs, err := table(m, sizes, demand+max(sizes))
+	if err != nil {
+		return nil, err
 	}
+	return walk(m, packs, demand, prefer)
+}
 
-	// Note: the following section is calculating possible packs and optimized the run.
-	// Adds up packs so we don't have to do it again.
-	const inf = math.MaxInt32
-	packs := make([]int64, target+1)
-	prev := make([]int64, target+1)
-	run := make([]int64, target+1)
+// noPacks marks a target of table that cannot be composed from the sizes.
+const noPacks = math.MaxInt32
+
+// table counts the fewest packs of `sizes` summing exactly to every target up to limit.
+func table(m *algorithms.Meter, sizes []int64, limit int64) ([]int64, error) {
+	if err := m.Reserve(limit + 1); err != nil {
+		return nil, err
+	}
+	packs := make([]int64, limit+1)
 	for i := range packs {
-		packs[i] = inf
+		packs[i] = noPacks
 	}
 	packs[0] = 0
 	for _, s := range sizes {
-		for t := s; t <= target; t++ {
+		for t := s; t <= limit; t++ {
+			if err := m.Tick(); err != nil {
+				return nil, err
+			}
 			if packs[t-s]+1 < packs[t] {
 				packs[t] = packs[t-s] + 1
-				prev[t] = s
-				if prev[t-s] == s {
-					run[t] = run[t-s] + 1
-				} else {
-					run[t] = 1
-				}
 			}
 		}
 	}
+	return packs, nil
+}
 
-	// Note: going back from the target and collecting the output.
+// walk reads the allocation of `demand` off a table built by table, nil if nothing covers it.
+func walk(m *algorithms.Meter, packs []int64, demand int64, prefer []int64) (map[int64]int64, error) {
+	// Note: searching for the first possible reachable target.
+	target := int64(-1)
+	for t := demand; t < int64(len(packs)); t++ {
+		if packs[t] != noPacks {
+			target = t
+			break
+		}
+	}
+	// Note: if not found, no solution is possible.
+	if target == -1 {
+		return nil, nil
+	}
+
+	// Note: going back from the target, always taking the most preferred pack an optimal solution has room for.
+	// This ends on the optimal solution with the most of the first preferred pack, then of the second, and so on.
 	out := make(map[int64]int64)
 	for t := target; t > 0; {
-		s := prev[t]
-		k := run[t]
-		out[s*g] += k // restore the original unit size from gcd
-		t -= s * k
+		if err := m.Tick(); err != nil {
+			return nil, err
+		}
+		for _, s := range prefer {
+			if s <= t && packs[t-s] == packs[t]-1 {
+				out[s]++
+				t -= s
+				break
+			}
+		}
 	}
 
-	return out
+	return out, nil
 }
 
-func gcd(xs []int64) int64 {
-	calc := func(a, b int64) int64 {
-		for b != 0 {
-			a, b = b, a%b
-		}
-		return a
+// ceilDiv returns a/b rounded up without overflowing near math.MaxInt64.
+func ceilDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 {
+		q++
 	}
+	return q
+}
 
-	g := xs[0]
-	for _, x := range xs[1:] {
-		g = calc(g, x)
+// second returns the second largest of xs, or 0 if there is only one.
+func second(xs []int64) int64 {
+	top := max(xs)
+	out := int64(0)
+	for _, x := range xs {
+		if x < top && x > out {
+			out = x
+		}
 	}
-	return g
+	return out
 }
 
 func max(xs []int64) int64 {
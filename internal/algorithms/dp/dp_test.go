@@ -1,8 +1,18 @@
 package dp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"math/big"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
 )
 
 func TestAllocate(t *testing.T) {
@@ -51,6 +61,790 @@ func TestAllocate(t *testing.T) {
 	}
 }
 
+func TestAllocate_SameAsExact(t *testing.T) {
+	// Note: every set goes well past the point where the largest pack is taken up front.
+	sets := []struct {
+		sizes []int64
+		upTo  int64
+	}{
+		{sizes: []int64{23, 31, 53}, upTo: 5_000},
+		{sizes: []int64{250, 500, 1000, 2000, 5000}, upTo: 100_000},
+		{sizes: []int64{6, 9, 20}, upTo: 1_000},
+		{sizes: []int64{7}, upTo: 100},
+	}
+
+	for _, set := range sets {
+		sizes := set.sizes
+		t.Run(fmt.Sprint(sizes), func(t *testing.T) {
+			for demand := int64(1); demand <= set.upTo; demand++ {
+				reduced := slices.Clone(sizes)
+				g := algorithms.GCD(reduced)
+				for i := range reduced {
+					reduced[i] /= g
+				}
+				want := make(map[int64]int64)
+				prefer := slices.Clone(reduced)
+				slices.Sort(prefer)
+				slices.Reverse(prefer)
+				out, _ := exact(unlimited(), reduced, ceilDiv(demand, g), prefer)
+				for s, k := range out {
+					want[s*g] = k
+				}
+
+				got := Allocate(slices.Clone(sizes), demand)
+				if err := cmp(got, want); err != nil {
+					t.Fatalf("demand %d: Allocate() mismatch:\n%s", demand, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestAllocate_MaxDemand(t *testing.T) {
+	sizes := []int64{23, 31, 53}
+
+	got := Allocate(slices.Clone(sizes), math.MaxInt64)
+
+	items := new(big.Int)
+	for s, k := range got {
+		items.Add(items, new(big.Int).Mul(big.NewInt(s), big.NewInt(k)))
+	}
+	if want := big.NewInt(math.MaxInt64); items.Cmp(want) != 0 {
+		t.Errorf("Allocate() covers %s items, want %s", items, want)
+	}
+}
+
+func TestAllocateBounded(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    []int64
+		stock    []int64
+		quantity int64
+		exp      map[int64]int64
+	}{
+		{
+			name:     "not enough stock",
+			sizes:    []int64{23, 31, 53},
+			stock:    []int64{1000, 1000, 300},
+			quantity: 500_000,
+			exp:      nil,
+		},
+		{
+			name:     "stock limits the search",
+			sizes:    []int64{23, 31, 53},
+			stock:    []int64{1000, 1000, 300},
+			quantity: 20_000,
+			exp: map[int64]int64{
+				23: 4,
+				31: 131,
+				53: 299,
+			},
+		},
+		{
+			name:     "out of stock size is skipped",
+			sizes:    []int64{250, 500, 1000},
+			stock:    []int64{0, 2, 1},
+			quantity: 251,
+			exp: map[int64]int64{
+				500: 1,
+			},
+		},
+		{
+			name:     "falls back to next best",
+			sizes:    []int64{250, 500, 1000},
+			stock:    []int64{0, 2, 1},
+			quantity: 1600,
+			exp: map[int64]int64{
+				500:  2,
+				1000: 1,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allocation := AllocateBounded(tc.sizes, tc.stock, tc.quantity)
+
+			if tc.exp == nil && allocation != nil {
+				t.Fatalf("AllocateBounded() = %v, want nil", allocation)
+			}
+			if err := cmp(allocation, tc.exp); err != nil {
+				t.Errorf("AllocateBounded() mismatch:\n%s", err.Error())
+			}
+		})
+	}
+}
+
+func TestAllocateBounded_BruteForce(t *testing.T) {
+	sizes := []int64{4, 6, 9}
+	stock := []int64{3, 1, 2}
+
+	for demand := int64(1); demand <= 36; demand++ {
+		bestItems, bestPacks := int64(-1), int64(-1)
+		for a := int64(0); a <= stock[0]; a++ {
+			for b := int64(0); b <= stock[1]; b++ {
+				for c := int64(0); c <= stock[2]; c++ {
+					items := a*sizes[0] + b*sizes[1] + c*sizes[2]
+					packs := a + b + c
+					if items < demand {
+						continue
+					}
+					if bestItems == -1 || items < bestItems || (items == bestItems && packs < bestPacks) {
+						bestItems, bestPacks = items, packs
+					}
+				}
+			}
+		}
+
+		got := AllocateBounded(sizes, stock, demand)
+		if bestItems == -1 {
+			if got != nil {
+				t.Errorf("demand %d: got %v, want nil", demand, got)
+			}
+			continue
+		}
+
+		items, packs := int64(0), int64(0)
+		for i, s := range sizes {
+			if got[s] > stock[i] {
+				t.Errorf("demand %d: %d packs of %d exceed stock %d", demand, got[s], s, stock[i])
+			}
+			items += got[s] * s
+			packs += got[s]
+		}
+		if items != bestItems || packs != bestPacks {
+			t.Errorf("demand %d: got %d items in %d packs, want %d items in %d packs", demand, items, packs, bestItems, bestPacks)
+		}
+	}
+}
+
+func TestAllocateCheapest(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    []int64
+		prices   []int64
+		quantity int64
+		exp      map[int64]int64
+	}{
+		{
+			name:     "larger pack is cheaper",
+			sizes:    []int64{250, 500},
+			prices:   []int64{100, 150},
+			quantity: 251,
+			exp: map[int64]int64{
+				500: 1,
+			},
+		},
+		{
+			name:     "smaller packs are cheaper",
+			sizes:    []int64{250, 500},
+			prices:   []int64{100, 250},
+			quantity: 500,
+			exp: map[int64]int64{
+				250: 2,
+			},
+		},
+		{
+			name:     "overfill when it saves money",
+			sizes:    []int64{23, 31, 53},
+			prices:   []int64{100, 120, 300},
+			quantity: 53,
+			exp: map[int64]int64{
+				23: 1,
+				31: 1,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allocation := AllocateCheapest(tc.sizes, tc.prices, tc.quantity)
+
+			if err := cmp(allocation, tc.exp); err != nil {
+				t.Errorf("AllocateCheapest() mismatch:\n%s", err.Error())
+			}
+		})
+	}
+}
+
+func TestAllocateCheapest_BruteForce(t *testing.T) {
+	sizes := []int64{4, 6, 9}
+	prices := []int64{5, 6, 10}
+
+	for demand := int64(1); demand <= 40; demand++ {
+		bestCost, bestItems, bestPacks := int64(-1), int64(0), int64(0)
+		for a := int64(0); a <= demand/sizes[0]+1; a++ {
+			for b := int64(0); b <= demand/sizes[1]+1; b++ {
+				for c := int64(0); c <= demand/sizes[2]+1; c++ {
+					items := a*sizes[0] + b*sizes[1] + c*sizes[2]
+					if items < demand {
+						continue
+					}
+					cost := a*prices[0] + b*prices[1] + c*prices[2]
+					packs := a + b + c
+					better := bestCost == -1 || cost < bestCost ||
+						(cost == bestCost && (items < bestItems || (items == bestItems && packs < bestPacks)))
+					if better {
+						bestCost, bestItems, bestPacks = cost, items, packs
+					}
+				}
+			}
+		}
+
+		got := AllocateCheapest(sizes, prices, demand)
+
+		cost, items, packs := int64(0), int64(0), int64(0)
+		for i, s := range sizes {
+			cost += got[s] * prices[i]
+			items += got[s] * s
+			packs += got[s]
+		}
+		if cost != bestCost || items != bestItems || packs != bestPacks {
+			t.Errorf("demand %d: got cost %d for %d items in %d packs, want cost %d for %d items in %d packs",
+				demand, cost, items, packs, bestCost, bestItems, bestPacks)
+		}
+	}
+}
+
+func TestAllocator_Budget(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Price: 100},
+		{ID: "L", Capacity: 31, Price: 120},
+		{ID: "XL", Capacity: 53, Price: 300},
+	}
+	opts := algorithms.Options{Objective: algorithms.MinCost}
+
+	t.Run("cells", func(t *testing.T) {
+		opts := opts
+		opts.Budget = algorithms.Budget{MaxCells: 1_000}
+
+		_, err := Allocator{}.Allocate(context.Background(), sizes, 500_000, opts)
+
+		var budgetErr *algorithms.BudgetError
+		if !errors.As(err, &budgetErr) || budgetErr.Resource != algorithms.Cells {
+			t.Errorf("Allocate() error = %v, want cells budget error", err)
+		}
+	})
+
+	t.Run("wall time", func(t *testing.T) {
+		opts := opts
+		opts.Budget = algorithms.Budget{MaxDuration: time.Nanosecond}
+
+		_, err := Allocator{}.Allocate(context.Background(), sizes, 500_000, opts)
+
+		var budgetErr *algorithms.BudgetError
+		if !errors.As(err, &budgetErr) || budgetErr.Resource != algorithms.WallTime {
+			t.Errorf("Allocate() error = %v, want wall time budget error", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := Allocator{}.Allocate(ctx, sizes, 500_000, opts)
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Allocate() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("within budget", func(t *testing.T) {
+		opts := opts
+		opts.Budget = algorithms.Budget{MaxCells: 1_000_000, MaxDuration: time.Minute}
+
+		got, err := Allocator{}.Allocate(context.Background(), sizes, 1_000, opts)
+		if err != nil {
+			t.Fatalf("Allocate() error = %v", err)
+		}
+		if len(got) == 0 {
+			t.Errorf("Allocate() = %v, want an allocation", got)
+		}
+	})
+}
+
+func TestAllocator_DemandTooLarge(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Price: 100},
+		{ID: "L", Capacity: 31, Price: 120},
+		{ID: "XL", Capacity: 53, Price: 300},
+	}
+
+	tests := []struct {
+		name string
+		opts algorithms.Options
+	}{
+		{name: "cost", opts: algorithms.Options{Objective: algorithms.MinCost}},
+		{name: "fewer sizes", opts: algorithms.Options{TieBreak: pack.TieBreak{Rule: pack.PreferFewerSizes}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Allocator{}.AllocateBatch(context.Background(), sizes, []int64{1_000, math.MaxInt64}, tc.opts)
+			if err != nil {
+				t.Fatalf("AllocateBatch() error = %v", err)
+			}
+			if got[0].Err != nil {
+				t.Errorf("AllocateBatch() demand 1000 error = %v", got[0].Err)
+			}
+			if !errors.Is(got[1].Err, algorithms.ErrDemandTooLarge) {
+				t.Errorf("AllocateBatch() huge demand error = %v, want %v", got[1].Err, algorithms.ErrDemandTooLarge)
+			}
+		})
+	}
+
+	t.Run("pareto", func(t *testing.T) {
+		_, err := Allocator{}.Pareto(context.Background(), sizes, math.MaxInt64/2, algorithms.Options{})
+		if !errors.Is(err, algorithms.ErrDemandTooLarge) {
+			t.Errorf("Pareto() error = %v, want %v", err, algorithms.ErrDemandTooLarge)
+		}
+	})
+}
+
+func TestAllocator_Pareto(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "A", Capacity: 4},
+		{ID: "B", Capacity: 6},
+		{ID: "C", Capacity: 9},
+	}
+
+	for demand := int64(1); demand <= 40; demand++ {
+		var brute []algorithms.Alternative
+		for a := int64(0); a <= demand/4+1; a++ {
+			for b := int64(0); b <= demand/6+1; b++ {
+				for c := int64(0); c <= demand/9+1; c++ {
+					items := 4*a + 6*b + 9*c
+					if items < demand {
+						continue
+					}
+					distinct := 0
+					for _, k := range []int64{a, b, c} {
+						if k > 0 {
+							distinct++
+						}
+					}
+					brute = append(brute, algorithms.Alternative{
+						Overfill:  items - demand,
+						PackCount: a + b + c,
+						Distinct:  distinct,
+					})
+				}
+			}
+		}
+		want := algorithms.ParetoFront(brute)
+
+		got, err := Allocator{}.Pareto(context.Background(), sizes, demand, algorithms.Options{})
+		if err != nil {
+			t.Fatalf("demand %d: Pareto() error = %v", demand, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("demand %d: Pareto() returned %d alternatives, want %d", demand, len(got), len(want))
+		}
+		for i := range got {
+			items, packs := int64(0), int64(0)
+			for id, k := range got[i].Packs {
+				size, _ := sizes.ByID(id)
+				items += size.Capacity * int64(k)
+				packs += int64(k)
+			}
+			if items-demand != got[i].Overfill || packs != got[i].PackCount {
+				t.Errorf("demand %d: alternative %v does not match its measures", demand, got[i])
+			}
+			if got[i].Overfill != want[i].Overfill || got[i].PackCount != want[i].PackCount || got[i].Distinct != want[i].Distinct {
+				t.Errorf("demand %d: alternative %d = %+v, want %+v", demand, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestAllocator_MaxOverfill(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Stock: 100, Price: 100},
+		{ID: "L", Capacity: 31, Stock: 100, Price: 120},
+		{ID: "XL", Capacity: 53, Stock: 100, Price: 300},
+	}
+	exact := algorithms.Tolerance{Limited: true}
+
+	tests := []struct {
+		name      string
+		demand    int64
+		objective algorithms.Objective
+		tolerance algorithms.Tolerance
+		want      map[pack.ID]pack.Quantity
+		wantErr   *algorithms.ToleranceError
+	}{
+		{
+			name:      "exact fill",
+			demand:    100,
+			tolerance: exact,
+			want:      map[pack.ID]pack.Quantity{"S": 3, "L": 1},
+		},
+		{
+			name:      "exact fill impossible",
+			demand:    24,
+			tolerance: exact,
+			wantErr:   &algorithms.ToleranceError{Allowed: 0, Closest: 7},
+		},
+		{
+			name:      "within units",
+			demand:    24,
+			tolerance: algorithms.Tolerance{Limited: true, Units: 7},
+			want:      map[pack.ID]pack.Quantity{"L": 1},
+		},
+		{
+			name:      "over percentage",
+			demand:    24,
+			tolerance: algorithms.Tolerance{Limited: true, BasisPoints: 2500},
+			wantErr:   &algorithms.ToleranceError{Allowed: 6, Closest: 7},
+		},
+		{
+			name:      "within percentage",
+			demand:    24,
+			tolerance: algorithms.Tolerance{Limited: true, BasisPoints: 3000},
+			want:      map[pack.ID]pack.Quantity{"L": 1},
+		},
+		{
+			name:      "cheapest within tolerance",
+			demand:    53,
+			objective: algorithms.MinCost,
+			tolerance: exact,
+			want:      map[pack.ID]pack.Quantity{"XL": 1},
+		},
+		{
+			name:      "cheapest without tolerance",
+			demand:    53,
+			objective: algorithms.MinCost,
+			want:      map[pack.ID]pack.Quantity{"S": 1, "L": 1},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, bounded := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/bounded=%v", tc.name, bounded), func(t *testing.T) {
+				got, err := Allocator{}.Allocate(context.Background(), sizes, tc.demand, algorithms.Options{
+					Bounded:     bounded,
+					Objective:   tc.objective,
+					MaxOverfill: tc.tolerance,
+				})
+				if tc.wantErr != nil {
+					var tolErr *algorithms.ToleranceError
+					if !errors.As(err, &tolErr) || *tolErr != *tc.wantErr {
+						t.Fatalf("Allocate() error = %v, want %v", err, tc.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Allocate() error = %v", err)
+				}
+				if !maps.Equal(got, tc.want) {
+					t.Errorf("Allocate() = %v, want %v", got, tc.want)
+				}
+			})
+		}
+	}
+}
+
+func TestAllocator_ParetoMaxOverfill(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23},
+		{ID: "L", Capacity: 31},
+		{ID: "XL", Capacity: 53},
+	}
+
+	got, err := Allocator{}.Pareto(context.Background(), sizes, 100, algorithms.Options{
+		MaxOverfill: algorithms.Tolerance{Limited: true, Units: 6},
+	})
+	if err != nil {
+		t.Fatalf("Pareto() error = %v", err)
+	}
+	for _, alt := range got {
+		if alt.Overfill > 6 {
+			t.Errorf("Pareto() returned %+v beyond the tolerance", alt)
+		}
+	}
+
+	_, err = Allocator{}.Pareto(context.Background(), sizes, 24, algorithms.Options{
+		MaxOverfill: algorithms.Tolerance{Limited: true},
+	})
+	var tolErr *algorithms.ToleranceError
+	if !errors.As(err, &tolErr) || tolErr.Closest != 7 {
+		t.Errorf("Pareto() error = %v, want closest overfill of 7", err)
+	}
+}
+
+func TestAllocator_AllocateBatch(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Stock: 40, Price: 100},
+		{ID: "L", Capacity: 31, Stock: 40, Price: 120, MinQuantity: 2},
+		{ID: "XL", Capacity: 53, Stock: 10, Price: 300},
+	}
+
+	demands := []int64{5_000, 1, 24}
+	for demand := int64(100); demand <= 3_000; demand += 37 {
+		demands = append(demands, demand)
+	}
+
+	tests := []struct {
+		name  string
+		sizes pack.Sizes
+		opts  algorithms.Options
+	}{
+		{name: "unbounded", sizes: pack.Sizes{{ID: "S", Capacity: 23}, {ID: "L", Capacity: 31}, {ID: "XL", Capacity: 53}}},
+		{name: "bounded", sizes: sizes, opts: algorithms.Options{Bounded: true}},
+		{name: "cheapest", sizes: sizes, opts: algorithms.Options{Objective: algorithms.MinCost}},
+		{name: "exact fill", sizes: sizes, opts: algorithms.Options{MaxOverfill: algorithms.Tolerance{Limited: true}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Allocator{}.AllocateBatch(context.Background(), tc.sizes, demands, tc.opts)
+			if err != nil {
+				t.Fatalf("AllocateBatch() error = %v", err)
+			}
+			if len(got) != len(demands) {
+				t.Fatalf("AllocateBatch() returned %d results, want %d", len(got), len(demands))
+			}
+
+			for i, demand := range demands {
+				want, wantErr := Allocator{}.Allocate(context.Background(), tc.sizes, demand, tc.opts)
+				if (got[i].Err == nil) != (wantErr == nil) || (wantErr != nil && got[i].Err.Error() != wantErr.Error()) {
+					t.Fatalf("demand %d: AllocateBatch() error = %v, want %v", demand, got[i].Err, wantErr)
+				}
+				if !maps.Equal(got[i].Packs, want) {
+					t.Errorf("demand %d: AllocateBatch() = %v, want %v", demand, got[i].Packs, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_TieBreak(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "one", Capacity: 1},
+		{ID: "two", Capacity: 2},
+		{ID: "three", Capacity: 3},
+	}
+
+	tests := []struct {
+		name     string
+		tieBreak pack.TieBreak
+		exp      map[pack.ID]pack.Quantity
+	}{
+		{
+			name:     "default prefers larger",
+			tieBreak: pack.TieBreak{},
+			exp:      map[pack.ID]pack.Quantity{"three": 1, "one": 1},
+		},
+		{
+			name:     "fewer sizes",
+			tieBreak: pack.TieBreak{Rule: pack.PreferFewerSizes},
+			exp:      map[pack.ID]pack.Quantity{"two": 2},
+		},
+		{
+			name:     "priority",
+			tieBreak: pack.TieBreak{Rule: pack.PreferPriority, Priority: []pack.ID{"two"}},
+			exp:      map[pack.ID]pack.Quantity{"two": 2},
+		},
+		{
+			name:     "priority of a smaller pack",
+			tieBreak: pack.TieBreak{Rule: pack.PreferPriority, Priority: []pack.ID{"one"}},
+			exp:      map[pack.ID]pack.Quantity{"one": 1, "three": 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, bounded := range []bool{false, true} {
+				sizes := slices.Clone(sizes)
+				for i := range sizes {
+					sizes[i].Stock = 10
+				}
+				opts := algorithms.Options{Bounded: bounded, TieBreak: tc.tieBreak}
+
+				got, err := Allocator{}.Allocate(context.Background(), sizes, 4, opts)
+				if err != nil {
+					t.Fatalf("Allocate() error = %v", err)
+				}
+				if !maps.Equal(got, tc.exp) {
+					t.Errorf("Allocate(bounded=%v) = %v, want %v", bounded, got, tc.exp)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_FewerSizesUnsupported(t *testing.T) {
+	sizes := make(pack.Sizes, maxParetoSizes+1)
+	for i := range sizes {
+		sizes[i] = pack.Size{ID: pack.ID(fmt.Sprint(i)), Capacity: int64(i + 1)}
+	}
+	opts := algorithms.Options{TieBreak: pack.TieBreak{Rule: pack.PreferFewerSizes}}
+
+	_, err := Allocator{}.Allocate(context.Background(), sizes, 100, opts)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Allocate() error = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
+
+func TestAllocator_TieBreakIgnoresOrder(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 6, Price: 6},
+		{ID: "M", Capacity: 9, Price: 9},
+		{ID: "L", Capacity: 15, Price: 15},
+		{ID: "XL", Capacity: 20, Price: 20},
+	}
+	reversed := slices.Clone(sizes)
+	slices.Reverse(reversed)
+
+	policies := []pack.TieBreak{
+		{Rule: pack.PreferLarger},
+		{Rule: pack.PreferFewerSizes},
+		{Rule: pack.PreferPriority, Priority: []pack.ID{"M", "S"}},
+	}
+	objectives := []algorithms.Objective{algorithms.MinOverfill, algorithms.MinCost}
+
+	for _, tb := range policies {
+		for _, objective := range objectives {
+			opts := algorithms.Options{Objective: objective, TieBreak: tb}
+			for demand := int64(1); demand <= 200; demand++ {
+				a, _ := Allocator{}.Allocate(context.Background(), sizes, demand, opts)
+				b, _ := Allocator{}.Allocate(context.Background(), reversed, demand, opts)
+				if !maps.Equal(a, b) {
+					t.Fatalf("%s/%s demand %d: %v != %v", tb.Rule, objective, demand, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestAllocator_Constraints(t *testing.T) {
+	base := pack.Sizes{
+		{ID: "A", Capacity: 4, MinQuantity: 2, Stock: 5},
+		{ID: "B", Capacity: 6, Multiple: 3, Stock: 7},
+		{ID: "C", Capacity: 9, MinQuantity: 2, Multiple: 2, Stock: 4},
+	}
+
+	for _, required := range [][]pack.ID{nil, {"B"}, {"A", "C"}} {
+		sizes, err := base.Requiring(required)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, bounded := range []bool{false, true} {
+			for demand := int64(1); demand <= 90; demand++ {
+				bestItems, bestPacks := int64(-1), int64(0)
+				for a := int64(0); a <= 25; a++ {
+					for b := int64(0); b <= 25; b++ {
+						for c := int64(0); c <= 25; c++ {
+							counts := []int64{a, b, c}
+							ok := true
+							for i, k := range counts {
+								ok = ok && sizes[i].Allows(pack.Quantity(k)) && (!bounded || k <= int64(sizes[i].Stock))
+							}
+							items := 4*a + 6*b + 9*c
+							if !ok || items < demand {
+								continue
+							}
+							if bestItems == -1 || items < bestItems || (items == bestItems && a+b+c < bestPacks) {
+								bestItems, bestPacks = items, a+b+c
+							}
+						}
+					}
+				}
+
+				got, err := Allocator{}.Allocate(context.Background(), sizes, demand, algorithms.Options{Bounded: bounded})
+				if bestItems == -1 {
+					if err == nil {
+						t.Errorf("required=%v bounded=%v demand %d: Allocate() = %v, want an error", required, bounded, demand, got)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("required=%v bounded=%v demand %d: Allocate() error = %v", required, bounded, demand, err)
+				}
+
+				items, packs := int64(0), int64(0)
+				for id, k := range got {
+					size, _ := sizes.ByID(id)
+					if !size.Allows(k) {
+						t.Errorf("required=%v bounded=%v demand %d: %d packs of %s break its constraints", required, bounded, demand, k, id)
+					}
+					items += size.Capacity * int64(k)
+					packs += int64(k)
+				}
+				if items != bestItems || packs != bestPacks {
+					t.Errorf("required=%v bounded=%v demand %d: got %d items in %d packs, want %d items in %d packs",
+						required, bounded, demand, items, packs, bestItems, bestPacks)
+				}
+			}
+		}
+	}
+}
+
+func TestAllocator_Infeasible(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "A", Capacity: 10, Multiple: 6, Stock: 5},
+	}
+
+	tests := []struct {
+		name   string
+		demand int64
+		want   error
+	}{
+		{name: "constraints", demand: 20, want: algorithms.ErrInfeasible},
+		{name: "stock", demand: 60, want: algorithms.ErrInsufficientStock},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Allocator{}.Allocate(context.Background(), sizes, tc.demand, algorithms.Options{Bounded: true})
+			if !errors.Is(err, tc.want) {
+				t.Errorf("Allocate() error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestAllocator_VerifyOptimal(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Stock: 9, Price: 100},
+		{ID: "L", Capacity: 31, Stock: 6, Price: 120},
+		{ID: "XL", Capacity: 53, MinQuantity: 2, Stock: 4, Price: 300},
+	}
+	fewer, err := pack.NewTieBreak(string(pack.PreferFewerSizes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		opts algorithms.Options
+	}{
+		{name: "unbounded"},
+		{name: "bounded", opts: algorithms.Options{Bounded: true}},
+		{name: "cheapest", opts: algorithms.Options{Objective: algorithms.MinCost, Bounded: true}},
+		{name: "fewer sizes", opts: algorithms.Options{TieBreak: fewer}},
+		{name: "within 10", opts: algorithms.Options{MaxOverfill: algorithms.Tolerance{Limited: true, Units: 10}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for demand := int64(1); demand <= 400; demand++ {
+				got, err := Allocator{}.Allocate(context.Background(), sizes, demand, tc.opts)
+				if err != nil {
+					continue
+				}
+				violations, err := algorithms.VerifyOptimal(context.Background(), sizes, demand, got, tc.opts)
+				if err != nil {
+					t.Fatalf("demand %d: VerifyOptimal() error = %v", demand, err)
+				}
+				if len(violations) > 0 {
+					t.Fatalf("demand %d: Allocate() = %v violates %v", demand, got, violations)
+				}
+			}
+		})
+	}
+}
+
 func cmp(a, b map[int64]int64) error {
 	if len(a) != len(b) {
 		return fmt.Errorf("len(a) != len(b)")
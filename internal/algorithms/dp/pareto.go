@@ -0,0 +1,101 @@
+package dp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// maxParetoSizes caps the sizes Pareto enumerates subsets of.
+const maxParetoSizes = 12
+
+// Pareto solves the demand once per subset of sizes, so each subset yields the fewest packs for every
+// overfill using at most that many distinct sizes. Overfill is never worth a whole pack, which bounds
+// the targets to look at.
+func (a Allocator) Pareto(ctx context.Context, sizes pack.Sizes, demand int64, opts algorithms.Options) ([]algorithms.Alternative, error) {
+	m := algorithms.MeterFor(ctx, opts)
+
+	p := newPlan(sizes, opts)
+	p.objective = algorithms.MinOverfill
+
+	all, g, _ := prepare(items(sizes, opts), p)
+	if len(all) == 0 {
+		return nil, algorithms.Infeasible(sizes, demand, opts)
+	}
+	if len(all) > maxParetoSizes {
+		return nil, fmt.Errorf("alternatives are limited to %d sizes, got %d", maxParetoSizes, len(all))
+	}
+
+	target := ceilDiv(demand, g)
+	base := max([]int64{target, floor(all)})
+	reach := span(all)
+	req := required(all)
+
+	var alts []algorithms.Alternative
+	for mask := 1; mask < 1<<len(all); mask++ {
+		if mask&req != req {
+			continue
+		}
+
+		sub := subset(all, mask)
+		total := int64(0)
+		for _, it := range sub {
+			if it.limit < 0 {
+				total = addSat(total, base+reach)
+				continue
+			}
+			total = addSat(total, mulSat(it.size, it.limit))
+		}
+		if total < target {
+			continue
+		}
+
+		limit := min(base+reach-1, total)
+		layers, err := solve(m, sub, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		// Note: within a subset only targets needing fewer packs than every smaller target are worth keeping.
+		last := layers[len(layers)-1]
+		best := int64(-1)
+		for t := target; t <= limit; t++ {
+			if !last[t].ok() || (best != -1 && last[t].packs >= best) {
+				continue
+			}
+			best = last[t].packs
+
+			dist := reconstruct(sub, layers, t)
+			out := make(map[pack.ID]pack.Quantity, len(dist))
+			for s, k := range dist {
+				size, _ := sizes.ByCapacity(s)
+				out[size.ID] = pack.Quantity(k)
+			}
+			alts = append(alts, algorithms.Alternative{
+				Packs:     out,
+				Overfill:  t*g - demand,
+				PackCount: best,
+				Distinct:  len(out),
+			})
+		}
+	}
+	if len(alts) == 0 {
+		return nil, algorithms.Infeasible(sizes, demand, opts)
+	}
+
+	closest := alts[0].Overfill
+	for _, alt := range alts {
+		closest = min(closest, alt.Overfill)
+	}
+	alts = slices.DeleteFunc(alts, func(alt algorithms.Alternative) bool {
+		return !opts.MaxOverfill.Accepts(demand, alt.Overfill)
+	})
+	if len(alts) == 0 {
+		return nil, &algorithms.ToleranceError{Allowed: opts.MaxOverfill.Allowed(demand), Closest: closest}
+	}
+
+	return algorithms.ParetoFront(alts), nil
+}
@@ -0,0 +1,451 @@
+package dp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+)
+
+// unreachable marks a target that cannot be composed from the packs.
+const unreachable = math.MaxInt64
+
+// maxSpan caps the targets a layered table spans. Those tables grow with the demand, so larger demands are
+// rejected up front instead of leaving their memory to the budget, which may not limit cells at all.
+const maxSpan = 1 << 24
+
+// tooLarge explains that a layered table would span limit+1 targets.
+func tooLarge(limit int64) error {
+	return fmt.Errorf("%w: tables spanning %d targets, at most %d are supported", algorithms.ErrDemandTooLarge, addSat(limit, 1), maxSpan)
+}
+
+// item is a pack size as seen by the solver. It is taken in steps of Size.Multiple packs,
+// with all the other fields counted per step.
+type item struct {
+	pack  int64 // capacity of a single pack
+	size  int64 // units covered by one step
+	step  int64 // packs per step
+	least int64 // fewest steps when used at all
+	limit int64 // most steps, negative means unlimited
+	cost  int64
+	must  bool // at least least steps are always taken
+}
+
+// single returns an item for a pack that can be taken one at a time.
+func single(size, limit, cost int64) item {
+	return item{pack: size, size: size, step: 1, least: 1, limit: limit, cost: cost}
+}
+
+// allows reports whether k steps of the item satisfy its constraints.
+func (it item) allows(k int64) bool {
+	if k == 0 {
+		return !it.must
+	}
+	return k >= it.least && (it.limit < 0 || k <= it.limit)
+}
+
+// value orders partial solutions by cost first and pack count second.
+type value struct {
+	cost  int64
+	packs int64
+}
+
+func (v value) ok() bool {
+	return v.cost != unreachable
+}
+
+func (v value) less(o value) bool {
+	if v.cost != o.cost {
+		return v.cost < o.cost
+	}
+	return v.packs < o.packs
+}
+
+// plus returns v with k more steps of it.
+func (v value) plus(k int64, it item) value {
+	return value{cost: v.cost + k*it.cost, packs: v.packs + k*it.step}
+}
+
+// AllocateBounded distributes `demand` into packs of `sizes` using at most stock[i] packs of sizes[i].
+// It returns nil when the stock cannot cover the demand.
+func AllocateBounded(sizes []int64, stock []int64, demand int64) map[int64]int64 {
+	items := make([]item, 0, len(sizes))
+	for i, s := range sizes {
+		items = append(items, single(s, stock[i], 0))
+	}
+	out, _ := allocate(unlimited(), items, []int64{demand}, plan{objective: algorithms.MinOverfill})
+	return out[0].dist
+}
+
+// AllocateCheapest distributes `demand` into packs of `sizes` minimizing the sum of prices[i] per pack.
+func AllocateCheapest(sizes []int64, prices []int64, demand int64) map[int64]int64 {
+	items := make([]item, 0, len(sizes))
+	for i, s := range sizes {
+		items = append(items, single(s, -1, prices[i]))
+	}
+	out, _ := allocate(unlimited(), items, []int64{demand}, plan{objective: algorithms.MinCost})
+	return out[0].dist
+}
+
+// outcome is the allocation of a single demand, a nil dist without an error means
+// the items cannot cover the demand.
+type outcome struct {
+	dist map[int64]int64
+	err  error
+}
+
+// allocate solves once for the largest of the demands and picks an allocation for each of them.
+func allocate(m *algorithms.Meter, items []item, demands []int64, p plan) ([]outcome, error) {
+	out := make([]outcome, len(demands))
+
+	items, g, total := prepare(items, p)
+	if len(items) == 0 {
+		return out, nil
+	}
+
+	// Note: a demand too large for the tables fails on its own, the others are still solved.
+	reach := func(scaled int64) int64 {
+		return min(addSat(max([]int64{scaled, floor(items)}), span(items)), total/g)
+	}
+	top := int64(0)
+	for i, demand := range demands {
+		if demand > total {
+			continue
+		}
+		scaled := ceilDiv(demand, g)
+		if limit := reach(scaled); limit >= maxSpan {
+			out[i].err = tooLarge(limit)
+			continue
+		}
+		top = max([]int64{top, scaled})
+	}
+	limit := reach(top)
+
+	layers, err := solve(m, items, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, demand := range demands {
+		if demand > total || out[i].err != nil {
+			continue
+		}
+		out[i], err = settle(m, items, layers, g, demand, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// settle picks the target for a demand on the solved layers and reconstructs its allocation.
+func settle(m *algorithms.Meter, items []item, layers [][]value, g, demand int64, p plan) (outcome, error) {
+	scaled := ceilDiv(demand, g)
+	last := layers[len(layers)-1]
+	if scaled >= int64(len(last)) {
+		return outcome{}, nil
+	}
+
+	top := int64(len(last)) - 1
+	allowed := p.tolerance.Allowed(demand)
+	if allowed >= 0 {
+		top = min(top, addSat(demand, allowed)/g)
+	}
+
+	target := pick(last[:top+1], scaled, p.objective)
+	if target == -1 {
+		closest := pick(last, scaled, algorithms.MinOverfill)
+		if closest == -1 || allowed < 0 {
+			return outcome{}, nil
+		}
+		return outcome{err: &algorithms.ToleranceError{Allowed: allowed, Closest: closest*g - demand}}, nil
+	}
+
+	if p.fewest {
+		dist, err := fewest(m, items, target, last[target])
+		if err != nil || dist != nil {
+			return outcome{dist: dist}, err
+		}
+	}
+
+	return outcome{dist: reconstruct(items, layers, target)}, nil
+}
+
+// prepare drops items that cannot be used, orders the rest from the least to the most preferred
+// and divides them by their gcd. It returns the prepared items, the gcd and the most units
+// the items can cover.
+func prepare(items []item, p plan) ([]item, int64, int64) {
+	// Note: sizes without enough stock can never be used, so they don't take part in the search.
+	unusable := func(it item) bool {
+		return it.limit >= 0 && it.limit < it.least
+	}
+	if slices.ContainsFunc(items, func(it item) bool { return it.must && unusable(it) }) {
+		return nil, 0, 0
+	}
+	items = slices.DeleteFunc(slices.Clone(items), unusable)
+	if len(items) == 0 {
+		return nil, 0, 0
+	}
+
+	total := int64(0)
+	for i, it := range items {
+		if p.objective != algorithms.MinCost {
+			items[i].cost = 0
+		}
+		if it.limit < 0 {
+			total = math.MaxInt64
+			continue
+		}
+		total = addSat(total, mulSat(it.size, it.limit))
+	}
+
+	// Note: reconstruction favours items processed last, so keep the preferred packs at the end.
+	rank := make(map[int64]int, len(p.prefer))
+	for i, s := range p.prefer {
+		rank[s] = len(p.prefer) - i
+	}
+	slices.SortFunc(items, func(a, b item) int {
+		if rank[a.pack] != rank[b.pack] {
+			return rank[a.pack] - rank[b.pack]
+		}
+		return int(a.pack - b.pack)
+	})
+
+	caps := make([]int64, len(items))
+	for i, it := range items {
+		caps[i] = it.size
+	}
+	g := algorithms.GCD(caps)
+	for i := range items {
+		items[i].size /= g
+	}
+
+	return items, g, total
+}
+
+// reconstruct walks the layers back from target and returns packs used per pack capacity.
+func reconstruct(items []item, layers [][]value, target int64) map[int64]int64 {
+	out := make(map[int64]int64)
+	for i := len(items) - 1; i >= 0; i-- {
+		it := items[i]
+		want := layers[i][target]
+		for k := target / it.size; k >= 0; k-- {
+			if !it.allows(k) {
+				continue
+			}
+			rest := target - k*it.size
+			if below := at(layers, i-1, rest); below.ok() && below.plus(k, it) == want {
+				if k > 0 {
+					out[it.pack] = k * it.step
+				}
+				target = rest
+				break
+			}
+		}
+	}
+	return out
+}
+
+// fewest finds the allocation reaching target at the given value with the fewest distinct items,
+// ties go to the preferred items. More than maxParetoSizes items are not supported.
+func fewest(m *algorithms.Meter, items []item, target int64, want value) (map[int64]int64, error) {
+	if len(items) > maxParetoSizes {
+		return nil, fmt.Errorf("preferring fewer sizes is limited to %d sizes, got %d: %w", maxParetoSizes, len(items), errors.ErrUnsupported)
+	}
+
+	req := required(items)
+	for k := 1; k <= len(items); k++ {
+		var best map[int64]int64
+		for mask := 1; mask < 1<<len(items); mask++ {
+			if bits.OnesCount(uint(mask)) != k || mask&req != req {
+				continue
+			}
+
+			sub := subset(items, mask)
+			layers, err := solve(m, sub, target)
+			if err != nil {
+				return nil, err
+			}
+			if layers[len(layers)-1][target] != want {
+				continue
+			}
+
+			dist := reconstruct(sub, layers, target)
+			if best == nil || preferred(items, dist, best) {
+				best = dist
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// subset returns the items picked by the bits of mask, in their original order.
+func subset(items []item, mask int) []item {
+	out := make([]item, 0, bits.OnesCount(uint(mask)))
+	for i, it := range items {
+		if mask&(1<<i) != 0 {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// required returns the mask of the items that must be taken.
+func required(items []item) int {
+	out := 0
+	for i, it := range items {
+		if it.must {
+			out |= 1 << i
+		}
+	}
+	return out
+}
+
+// preferred reports whether a uses more of the preferred items than b, comparing the most preferred first.
+func preferred(items []item, a, b map[int64]int64) bool {
+	for i := len(items) - 1; i >= 0; i-- {
+		if s := items[i].pack; a[s] != b[s] {
+			return a[s] > b[s]
+		}
+	}
+	return false
+}
+
+// span returns the most units dropping a single item can take away from a solution.
+// A solution overshooting by that much can always lose packs and still meet the demand.
+func span(items []item) int64 {
+	out := int64(0)
+	for _, it := range items {
+		out = max([]int64{out, mulSat(it.least, it.size)})
+	}
+	return out
+}
+
+// floor returns the fewest units the items that must be taken cover together.
+// Below it no solution exists, so it raises the demand the same way.
+func floor(items []item) int64 {
+	out := int64(0)
+	for _, it := range items {
+		if it.must {
+			out = addSat(out, mulSat(it.least, it.size))
+		}
+	}
+	return out
+}
+
+// solve returns layers where layers[i][t] is the best value of items[:i+1] summing exactly to t.
+func solve(m *algorithms.Meter, items []item, limit int64) ([][]value, error) {
+	if limit >= maxSpan {
+		return nil, tooLarge(limit)
+	}
+	if err := m.Reserve(mulSat(int64(len(items)), limit+1)); err != nil {
+		return nil, err
+	}
+
+	layers := make([][]value, len(items))
+	for i, it := range items {
+		layers[i] = make([]value, limit+1)
+		if err := layer(m, layers, i, it); err != nil {
+			return nil, err
+		}
+	}
+	return layers, nil
+}
+
+// at returns layers[i][t], where layer -1 only reaches the empty sum.
+func at(layers [][]value, i int, t int64) value {
+	if i >= 0 {
+		return layers[i][t]
+	}
+	if t == 0 {
+		return value{}
+	}
+	return value{cost: unreachable}
+}
+
+// layer fills layers[i][t] with the minimum of layers[i-1][t-k*size] plus k steps
+// over k = 0, unless the item must be taken, and least <= k <= limit.
+func layer(m *algorithms.Meter, layers [][]value, i int, it item) error {
+	type entry struct {
+		j int64
+		v value
+	}
+
+	next := layers[i]
+	n := int64(len(next))
+	window := make([]entry, 0, n/it.size+1)
+	for r := int64(0); r < it.size && r < n; r++ {
+		// Note: sliding window minimum of prev[r+j*size]-j along the residue class r,
+		// lagging least steps behind so that every candidate takes at least that many.
+		window = window[:0]
+		for j := int64(0); r+j*it.size < n; j++ {
+			if err := m.Tick(); err != nil {
+				return err
+			}
+			t := r + j*it.size
+			if jj := j - it.least; jj >= 0 {
+				if prev := at(layers, i-1, r+jj*it.size); prev.ok() {
+					v := prev.plus(-jj, it)
+					for len(window) > 0 && !window[len(window)-1].v.less(v) {
+						window = window[:len(window)-1]
+					}
+					window = append(window, entry{j: jj, v: v})
+				}
+			}
+			for it.limit >= 0 && len(window) > 0 && window[0].j < j-it.limit {
+				window = window[1:]
+			}
+
+			best := value{cost: unreachable}
+			if !it.must {
+				best = at(layers, i-1, t)
+			}
+			if len(window) > 0 {
+				if v := window[0].v.plus(j, it); !best.ok() || v.less(best) {
+					best = v
+				}
+			}
+			next[t] = best
+		}
+	}
+	return nil
+}
+
+// pick returns the target the objective settles on, or -1 if nothing at or above demand is reachable.
+func pick(last []value, demand int64, objective algorithms.Objective) int64 {
+	target := int64(-1)
+	for t := demand; t < int64(len(last)); t++ {
+		if !last[t].ok() {
+			continue
+		}
+		if objective != algorithms.MinCost {
+			return t
+		}
+		if target == -1 || last[t].cost < last[target].cost {
+			target = t
+		}
+	}
+	return target
+}
+
+func mulSat(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+func addSat(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
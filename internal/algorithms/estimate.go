@@ -0,0 +1,33 @@
+package algorithms
+
+import (
+	"context"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Gap is how far an allocation may be from the optimal one, measured separately on each criterion.
+// Cost only counts for the MinCost objective.
+type Gap struct {
+	Overfill int64 `json:"overfill"`
+	Packs    int64 `json:"packs"`
+	Cost     int64 `json:"cost"`
+}
+
+// Within reports whether no measure of g exceeds the one of limit.
+func (g Gap) Within(limit Gap) bool {
+	return g.Overfill <= limit.Overfill && g.Packs <= limit.Packs && g.Cost <= limit.Cost
+}
+
+// Estimate is an allocation along with how far it may be from the optimal one.
+type Estimate struct {
+	Packs map[pack.ID]pack.Quantity
+	Gap   Gap
+	// Verified is set when Gap was measured against an exact allocation instead of bounded from below.
+	Verified bool
+}
+
+// Estimator is implemented by heuristic allocators that bound the quality of their answer.
+type Estimator interface {
+	Estimate(ctx context.Context, sizes pack.Sizes, demand int64, opts Options) (Estimate, error)
+}
@@ -0,0 +1,143 @@
+package algorithms
+
+import (
+	"context"
+	"math"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Feasibility describes which quantities a set of sizes can fill exactly.
+// It only looks at capacities, stock and the constraints of the sizes are ignored.
+type Feasibility struct {
+	// GCD of the capacities, only its multiples can ever be filled exactly.
+	GCD int64 `json:"gcd"`
+	// Frobenius is the largest multiple of GCD that cannot be filled exactly, or -1 when there is none.
+	Frobenius int64 `json:"frobenius"`
+	// Unreachable lists every multiple of GCD up to Frobenius that cannot be filled exactly.
+	Unreachable []int64 `json:"unreachable"`
+	// WorstOverfill is the most an allocation with the smallest overfill ever overshoots any demand.
+	WorstOverfill int64 `json:"worst_overfill"`
+}
+
+// AnalyzeFeasibility reports the Frobenius number of the sizes along with the quantities below it
+// that cannot be filled exactly. The work grows with the smallest capacity and the Frobenius
+// number, both are metered against budget.
+func AnalyzeFeasibility(ctx context.Context, sizes pack.Sizes, budget Budget) (Feasibility, error) {
+	m := NewMeter(ctx, budget)
+
+	caps := sizes.Capacities()
+	g := GCD(caps)
+	for i := range caps {
+		caps[i] /= g
+	}
+
+	// Note: least[r] is the smallest sum of packs leaving remainder r when divided by the smallest size.
+	// Every larger quantity with the same remainder is reachable too, by adding more of the smallest size.
+	least, err := residues(m, caps)
+	if err != nil {
+		return Feasibility{}, err
+	}
+	step := int64(len(least))
+
+	frobenius := int64(-1)
+	for _, l := range least {
+		frobenius = max(frobenius, l-step)
+	}
+
+	out := Feasibility{
+		GCD:       g,
+		Frobenius: -1,
+	}
+	if frobenius < 0 {
+		out.WorstOverfill = g - 1
+		return out, nil
+	}
+
+	if err := m.Reserve(frobenius); err != nil {
+		return Feasibility{}, err
+	}
+
+	// Note: the worst overfill is hit right above a reachable quantity followed by the longest gap.
+	gap, last := int64(1), int64(0)
+	for n := int64(1); n <= frobenius+1; n++ {
+		if err := m.Tick(); err != nil {
+			return Feasibility{}, err
+		}
+		if n < least[n%step] {
+			out.Unreachable = append(out.Unreachable, n*g)
+			continue
+		}
+		gap = max(gap, n-last)
+		last = n
+	}
+
+	out.Frobenius = frobenius * g
+	out.WorstOverfill = gap*g - 1
+
+	return out, nil
+}
+
+// residues returns the smallest sum of sizes for every remainder modulo the smallest size.
+// The sizes are expected to be divided by their gcd, so every remainder is reached.
+func residues(m *Meter, sizes []int64) ([]int64, error) {
+	step := sizes[0]
+	for _, s := range sizes[1:] {
+		step = min(step, s)
+	}
+
+	if err := m.Reserve(step); err != nil {
+		return nil, err
+	}
+	least := make([]int64, step)
+	for i := range least {
+		least[i] = math.MaxInt64
+	}
+	least[0] = 0
+
+	// Note: adding a size walks the remainders in cycles, starting each cycle at its smallest
+	// entry makes one pass around it enough to relax every entry.
+	for _, s := range sizes {
+		if s%step == 0 {
+			continue
+		}
+		d := GCD([]int64{step, s % step})
+		for start := int64(0); start < d; start++ {
+			from := start
+			for r, i := (start+s)%step, int64(1); i < step/d; r, i = (r+s)%step, i+1 {
+				if least[r] < least[from] {
+					from = r
+				}
+			}
+			if least[from] == math.MaxInt64 {
+				continue
+			}
+			for r, i := from, int64(0); i < step/d; i++ {
+				if err := m.Tick(); err != nil {
+					return nil, err
+				}
+				next := (r + s) % step
+				least[next] = min(least[next], least[r]+s)
+				r = next
+			}
+		}
+	}
+
+	return least, nil
+}
+
+// GCD returns the greatest common divisor of xs.
+func GCD(xs []int64) int64 {
+	calc := func(a, b int64) int64 {
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a
+	}
+
+	g := xs[0]
+	for _, x := range xs[1:] {
+		g = calc(g, x)
+	}
+	return g
+}
@@ -0,0 +1,107 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestAnalyzeFeasibility(t *testing.T) {
+	tests := []struct {
+		capacities []int64
+		want       Feasibility
+	}{
+		{
+			capacities: []int64{3, 5},
+			want:       Feasibility{GCD: 1, Frobenius: 7, Unreachable: []int64{1, 2, 4, 7}, WorstOverfill: 2},
+		},
+		{
+			capacities: []int64{4, 6},
+			want:       Feasibility{GCD: 2, Frobenius: 2, Unreachable: []int64{2}, WorstOverfill: 3},
+		},
+		{
+			capacities: []int64{250, 500, 1000, 2000, 5000},
+			want:       Feasibility{GCD: 250, Frobenius: -1, WorstOverfill: 249},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.capacities), func(t *testing.T) {
+			got, err := AnalyzeFeasibility(context.Background(), sizesOf(tt.capacities), Budget{})
+			if err != nil {
+				t.Fatalf("AnalyzeFeasibility() error = %v", err)
+			}
+			if got.GCD != tt.want.GCD || got.Frobenius != tt.want.Frobenius ||
+				!slices.Equal(got.Unreachable, tt.want.Unreachable) || got.WorstOverfill != tt.want.WorstOverfill {
+				t.Errorf("AnalyzeFeasibility() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeFeasibility_BruteForce(t *testing.T) {
+	sets := [][]int64{
+		{6, 9, 20},
+		{23, 31, 53},
+		{10, 15, 35},
+		{12, 18, 27, 40},
+		{7},
+	}
+
+	for _, capacities := range sets {
+		t.Run(fmt.Sprint(capacities), func(t *testing.T) {
+			got, err := AnalyzeFeasibility(context.Background(), sizesOf(capacities), Budget{})
+			if err != nil {
+				t.Fatalf("AnalyzeFeasibility() error = %v", err)
+			}
+
+			const bound = 5_000
+			reachable := make([]bool, bound+slices.Max(capacities)+1)
+			reachable[0] = true
+			for _, s := range capacities {
+				for n := s; n < int64(len(reachable)); n++ {
+					reachable[n] = reachable[n] || reachable[n-s]
+				}
+			}
+
+			var unreachable []int64
+			frobenius, worst := int64(-1), int64(0)
+			for n := int64(1); n <= bound; n++ {
+				if n%got.GCD == 0 && !reachable[n] {
+					unreachable = append(unreachable, n)
+					frobenius = n
+				}
+				next := n
+				for !reachable[next] {
+					next++
+				}
+				worst = max(worst, next-n)
+			}
+
+			if got.Frobenius != frobenius || !slices.Equal(got.Unreachable, unreachable) || got.WorstOverfill != worst {
+				t.Errorf("AnalyzeFeasibility() = %+v, want frobenius %d, worst overfill %d and %d unreachable",
+					got, frobenius, worst, len(unreachable))
+			}
+		})
+	}
+}
+
+func TestAnalyzeFeasibility_Budget(t *testing.T) {
+	_, err := AnalyzeFeasibility(context.Background(), sizesOf([]int64{1_000_003, 1_000_033}), Budget{MaxCells: 1_000_000})
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Resource != Cells {
+		t.Errorf("AnalyzeFeasibility() error = %v, want a cells budget error", err)
+	}
+}
+
+func sizesOf(capacities []int64) pack.Sizes {
+	out := make(pack.Sizes, len(capacities))
+	for i, c := range capacities {
+		out[i] = pack.Size{ID: pack.ID(fmt.Sprint(c)), Capacity: c}
+	}
+	return out
+}
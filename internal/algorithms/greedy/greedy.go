@@ -0,0 +1,258 @@
+package greedy
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Allocator takes as many packs of the best size as fit, moves on to the next one and rounds the rest up
+// with a single size. It looks at every size a constant number of times, so the answer can be far from
+// optimal, Estimate reports how far.
+type Allocator struct {
+	// Verify, when set, measures the gap against its allocation for demands up to VerifyUpTo.
+	Verify     algorithms.Allocator
+	VerifyUpTo int64
+}
+
+func (a Allocator) Allocate(ctx context.Context, sizes pack.Sizes, demand int64, opts algorithms.Options) (map[pack.ID]pack.Quantity, error) {
+	est, err := a.Estimate(ctx, sizes, demand, opts)
+	if err != nil {
+		return nil, err
+	}
+	return est.Packs, nil
+}
+
+// Estimate allocates the demand and bounds the gap to the optimal allocation from below: overfill by
+// the gcd of the sizes, packs by the largest size and cost by the cheapest price per unit.
+func (a Allocator) Estimate(ctx context.Context, sizes pack.Sizes, demand int64, opts algorithms.Options) (algorithms.Estimate, error) {
+	items := newItems(sizes, opts)
+	if len(items) == 0 {
+		return algorithms.Estimate{}, algorithms.Infeasible(sizes, demand, opts)
+	}
+
+	// Note: the best size comes first, the largest for overfill and the cheapest per unit for cost.
+	slices.SortStableFunc(items, func(a, b item) int {
+		if opts.Objective == algorithms.MinCost {
+			if c := cmp.Compare(a.cost*b.units, b.cost*a.units); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(b.units, a.units)
+	})
+
+	rest := demand
+	for i := range items {
+		it := &items[i]
+		k := max(rest, 0) / it.units
+		if it.must {
+			k = max(k, it.least)
+		}
+		if it.limit >= 0 {
+			k = min(k, it.limit)
+		}
+		if k < it.least {
+			if it.must {
+				return algorithms.Estimate{}, algorithms.Infeasible(sizes, demand, opts)
+			}
+			k = 0
+		}
+		it.count = k
+		rest -= k * it.units
+	}
+
+	if rest > 0 && !roundUp(items, rest, opts.Objective) {
+		// Note: no single size covers the rest, keep adding whatever is left in order.
+		for i := range items {
+			it := &items[i]
+			add := ceilDiv(rest, it.units)
+			if it.count == 0 {
+				add = max(add, it.least)
+			}
+			if it.limit >= 0 {
+				add = min(add, it.limit-it.count)
+			}
+			if it.count+add < it.least || add <= 0 {
+				continue
+			}
+			it.count += add
+			rest -= add * it.units
+			if rest <= 0 {
+				break
+			}
+		}
+		if rest > 0 {
+			return algorithms.Estimate{}, algorithms.Infeasible(sizes, demand, opts)
+		}
+	}
+
+	out := algorithms.Estimate{Packs: make(map[pack.ID]pack.Quantity)}
+	for _, it := range items {
+		if it.count > 0 {
+			out.Packs[it.id] = pack.Quantity(it.count * it.step)
+		}
+	}
+
+	overfill := -rest
+	if !opts.MaxOverfill.Accepts(demand, overfill) {
+		return algorithms.Estimate{}, &algorithms.ToleranceError{Allowed: opts.MaxOverfill.Allowed(demand), Closest: overfill}
+	}
+
+	got := measure(sizes, out.Packs, demand)
+	if a.Verify != nil && demand <= a.VerifyUpTo {
+		exact, err := a.Verify.Allocate(ctx, sizes, demand, opts)
+		if err == nil {
+			want := measure(sizes, exact, demand)
+			out.Gap = algorithms.Gap{
+				Overfill: max(got.Overfill-want.Overfill, 0),
+				Packs:    max(got.Packs-want.Packs, 0),
+				Cost:     max(got.Cost-want.Cost, 0),
+			}
+			out.Verified = true
+		}
+	}
+
+	if !out.Verified {
+		low := lowerBound(items, demand)
+		out.Gap = algorithms.Gap{
+			Overfill: got.Overfill - low.Overfill,
+			Packs:    got.Packs - low.Packs,
+			Cost:     got.Cost - low.Cost,
+		}
+	}
+	if opts.Objective != algorithms.MinCost {
+		out.Gap.Cost = 0
+	}
+
+	return out, nil
+}
+
+// roundUp covers the rest with more of a single size, the one overshooting least or, for cost, the cheapest.
+// It reports false when no single size covers it.
+func roundUp(items []item, rest int64, objective algorithms.Objective) bool {
+	best, bestOver, bestCost := -1, int64(0), int64(0)
+	for i, it := range items {
+		add := ceilDiv(rest, it.units)
+		if it.count == 0 {
+			add = max(add, it.least)
+		}
+		if it.limit >= 0 && it.count+add > it.limit {
+			continue
+		}
+		over, cost := add*it.units-rest, add*it.cost
+		better := best == -1 || over < bestOver
+		if objective == algorithms.MinCost {
+			better = best == -1 || cost < bestCost || (cost == bestCost && over < bestOver)
+		}
+		if better {
+			best, bestOver, bestCost = i, over, cost
+		}
+	}
+	if best == -1 {
+		return false
+	}
+
+	items[best].count += ceilDiv(rest+bestOver, items[best].units)
+	return true
+}
+
+// lowerBound returns measures no allocation of demand can go below.
+func lowerBound(items []item, demand int64) algorithms.Gap {
+	units := make([]int64, len(items))
+	capacity, ratio := int64(0), items[0]
+	for i, it := range items {
+		units[i] = it.units
+		capacity = max(capacity, it.capacity)
+		if it.cost*ratio.units < ratio.cost*it.units {
+			ratio = it
+		}
+	}
+
+	g := algorithms.GCD(units)
+	overfill := ceilDiv(demand, g)*g - demand
+	return algorithms.Gap{
+		Overfill: overfill,
+		Packs:    ceilDiv(demand+overfill, capacity),
+		Cost:     costBound(demand, ratio.cost, ratio.units),
+	}
+}
+
+// costBound is the least a number of units can cost at cost per units, rounded up.
+func costBound(demand, cost, units int64) int64 {
+	hi, lo := bits.Mul64(uint64(demand), uint64(cost))
+	if hi >= uint64(units) {
+		return math.MaxInt64
+	}
+	q, r := bits.Div64(hi, lo, uint64(units))
+	if r != 0 {
+		q++
+	}
+	return int64(min(q, math.MaxInt64))
+}
+
+// measure returns the overfill, packs and cost of an allocation.
+func measure(sizes pack.Sizes, packs map[pack.ID]pack.Quantity, demand int64) algorithms.Gap {
+	var out algorithms.Gap
+	items := int64(0)
+	for id, k := range packs {
+		s, _ := sizes.ByID(id)
+		items += s.Capacity * int64(k)
+		out.Packs += int64(k)
+		out.Cost += s.Price * int64(k)
+	}
+	out.Overfill = items - demand
+	return out
+}
+
+// item is a pack size as seen by the allocator, taken in steps of Size.Multiple packs.
+type item struct {
+	id       pack.ID
+	capacity int64
+	units    int64 // per step
+	step     int64 // packs per step
+	least    int64 // fewest steps when used at all
+	limit    int64 // most steps, negative means unlimited
+	cost     int64 // per step
+	must     bool
+	count    int64 // steps taken
+}
+
+// newItems converts sizes into items, skipping the ones without enough stock to be used at all.
+func newItems(sizes pack.Sizes, opts algorithms.Options) []item {
+	out := make([]item, 0, len(sizes))
+	for _, s := range sizes {
+		step := max(1, int64(s.Multiple))
+		limit := int64(-1)
+		if opts.Bounded {
+			limit = int64(s.Stock) / step
+		}
+		it := item{
+			id:       s.ID,
+			capacity: s.Capacity,
+			units:    s.Capacity * step,
+			step:     step,
+			least:    max(1, ceilDiv(int64(s.MinQuantity), step)),
+			limit:    limit,
+			cost:     s.Price * step,
+			must:     s.Required,
+		}
+		if it.limit >= 0 && it.limit < it.least && !it.must {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+func ceilDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 {
+		q++
+	}
+	return q
+}
@@ -0,0 +1,136 @@
+package greedy
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/algorithms/dp"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestEstimate(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "250", Capacity: 250},
+		{ID: "500", Capacity: 500},
+		{ID: "1000", Capacity: 1000},
+	}
+
+	tests := []struct {
+		name   string
+		demand int64
+		want   map[pack.ID]pack.Quantity
+		gap    algorithms.Gap
+	}{
+		{
+			name:   "exact",
+			demand: 1750,
+			want:   map[pack.ID]pack.Quantity{"1000": 1, "500": 1, "250": 1},
+			gap:    algorithms.Gap{Packs: 1},
+		},
+		{
+			name:   "rounds up with the smallest overshoot",
+			demand: 251,
+			want:   map[pack.ID]pack.Quantity{"250": 2},
+			gap:    algorithms.Gap{Packs: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Allocator{}.Estimate(context.Background(), sizes, tc.demand, algorithms.Options{})
+			if err != nil {
+				t.Fatalf("Estimate() error = %v", err)
+			}
+			if !maps.Equal(got.Packs, tc.want) {
+				t.Errorf("Estimate() = %v, want %v", got.Packs, tc.want)
+			}
+			if got.Gap != tc.gap || got.Verified {
+				t.Errorf("Estimate() gap = %+v (verified %v), want %+v", got.Gap, got.Verified, tc.gap)
+			}
+		})
+	}
+}
+
+func TestEstimate_BoundHolds(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Stock: 30, Price: 100},
+		{ID: "L", Capacity: 31, Stock: 20, Price: 120},
+		{ID: "XL", Capacity: 53, Stock: 8, Price: 300},
+	}
+	constrained := pack.Sizes{
+		{ID: "A", Capacity: 4, MinQuantity: 2, Stock: 9, Price: 5},
+		{ID: "B", Capacity: 6, Multiple: 3, Stock: 12, Price: 6},
+		{ID: "C", Capacity: 9, MinQuantity: 2, Multiple: 2, Stock: 8, Price: 10},
+	}
+
+	cases := []struct {
+		name  string
+		sizes pack.Sizes
+		opts  algorithms.Options
+	}{
+		{name: "unbounded", sizes: sizes},
+		{name: "bounded", sizes: sizes, opts: algorithms.Options{Bounded: true}},
+		{name: "cheapest", sizes: sizes, opts: algorithms.Options{Objective: algorithms.MinCost}},
+		{name: "constrained", sizes: constrained, opts: algorithms.Options{Bounded: true}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for demand := int64(1); demand <= 1_200; demand++ {
+				exact, err := dp.Allocator{}.Allocate(context.Background(), tc.sizes, demand, tc.opts)
+				if err != nil {
+					continue
+				}
+
+				got, err := Allocator{}.Estimate(context.Background(), tc.sizes, demand, tc.opts)
+				if err != nil {
+					// Note: the heuristic may miss allocations, the service falls back to DP then.
+					continue
+				}
+
+				have, want := measure(tc.sizes, got.Packs, demand), measure(tc.sizes, exact, demand)
+				if have.Overfill < 0 {
+					t.Fatalf("demand %d: Estimate() = %v does not cover the demand", demand, got.Packs)
+				}
+				for id, k := range got.Packs {
+					size, _ := tc.sizes.ByID(id)
+					if !size.Allows(k) || (tc.opts.Bounded && k > size.Stock) {
+						t.Fatalf("demand %d: %d packs of %s break its constraints", demand, k, id)
+					}
+				}
+				costly := tc.opts.Objective == algorithms.MinCost && have.Cost-want.Cost > got.Gap.Cost
+				if have.Overfill-want.Overfill > got.Gap.Overfill || have.Packs-want.Packs > got.Gap.Packs || costly {
+					t.Fatalf("demand %d: Estimate() gap %+v understates %+v against %+v", demand, got.Gap, have, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEstimate_Verify(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23},
+		{ID: "L", Capacity: 31},
+		{ID: "XL", Capacity: 53},
+	}
+	a := Allocator{Verify: dp.Allocator{}, VerifyUpTo: 1_000}
+
+	got, err := a.Estimate(context.Background(), sizes, 100, algorithms.Options{})
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+	// Note: 53 + 31 + 23 overshoots by 7 where 3×23 + 31 fills exactly.
+	if want := (algorithms.Gap{Overfill: 7}); !got.Verified || got.Gap != want {
+		t.Errorf("Estimate() gap = %+v (verified %v), want %+v verified", got.Gap, got.Verified, want)
+	}
+
+	got, err = a.Estimate(context.Background(), sizes, 100_000, algorithms.Options{})
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+	if got.Verified {
+		t.Errorf("Estimate() verified a demand above VerifyUpTo")
+	}
+}
@@ -0,0 +1,60 @@
+package algorithms
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Alternative is a candidate allocation together with the measures it is compared by.
+type Alternative struct {
+	Packs     map[pack.ID]pack.Quantity
+	Overfill  int64
+	PackCount int64
+	Distinct  int
+}
+
+// dominates reports whether a is no worse than b on every measure and better on at least one.
+func (a Alternative) dominates(b Alternative) bool {
+	if a.Overfill > b.Overfill || a.PackCount > b.PackCount || a.Distinct > b.Distinct {
+		return false
+	}
+	return a.Overfill < b.Overfill || a.PackCount < b.PackCount || a.Distinct < b.Distinct
+}
+
+// ParetoAllocator is implemented by allocators that can list trade-offs instead of a single answer.
+type ParetoAllocator interface {
+	// Pareto returns every allocation not dominated on overfill, pack count and distinct sizes used,
+	// ordered by overfill, then pack count, then distinct sizes.
+	Pareto(ctx context.Context, sizes pack.Sizes, demand int64, opts Options) ([]Alternative, error)
+}
+
+// ParetoFront drops dominated and repeated alternatives and orders the rest like ParetoAllocator does.
+func ParetoFront(alts []Alternative) []Alternative {
+	out := make([]Alternative, 0, len(alts))
+	for i, a := range alts {
+		keep := true
+		for j, b := range alts {
+			same := a.Overfill == b.Overfill && a.PackCount == b.PackCount && a.Distinct == b.Distinct
+			if b.dominates(a) || (same && j < i) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, a)
+		}
+	}
+
+	slices.SortFunc(out, func(a, b Alternative) int {
+		return cmp.Or(
+			cmp.Compare(a.Overfill, b.Overfill),
+			cmp.Compare(a.PackCount, b.PackCount),
+			cmp.Compare(a.Distinct, b.Distinct),
+		)
+	})
+
+	return out
+}
@@ -0,0 +1,94 @@
+package algorithms
+
+import (
+	"context"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// MostWithin finds an allocation covering as many items as possible without going over limit, within the
+// stock of the sizes when opts.Bounded. Unlike Allocate it never overshoots, it is meant for shipping what
+// is on hand when the stock cannot cover a demand. The constraints of the sizes are kept, ErrInfeasible is
+// returned when they rule out every allocation, such as a required size out of stock.
+func MostWithin(ctx context.Context, sizes pack.Sizes, limit int64, opts Options) (map[pack.ID]pack.Quantity, error) {
+	m := MeterFor(ctx, opts)
+	limit = max(limit, 0)
+
+	if err := m.Reserve(mulSat(int64(len(sizes))+1, limit+1)); err != nil {
+		return nil, err
+	}
+
+	// Note: rows[i][u] reports whether sizes[:i] can cover exactly u items.
+	rows := make([][]bool, len(sizes)+1)
+	rows[0] = make([]bool, limit+1)
+	rows[0][0] = true
+	for i, s := range sizes {
+		prev, cur := rows[i], make([]bool, limit+1)
+		if !s.Required {
+			copy(cur, prev)
+		}
+
+		lo, hi, step := span(s, limit, opts)
+		// Note: along every chain of totals step apart, u is covered when one of the lo..hi totals before
+		// it was, last remembers the latest of them.
+		for r := int64(0); r < step && r <= limit && lo <= hi; r++ {
+			last := int64(-1)
+			for idx, u := int64(0), r; u <= limit; idx, u = idx+1, u+step {
+				if err := m.Tick(); err != nil {
+					return nil, err
+				}
+				if idx >= lo && prev[r+(idx-lo)*step] {
+					last = idx - lo
+				}
+				if last >= 0 && last >= idx-hi {
+					cur[u] = true
+				}
+			}
+		}
+		rows[i+1] = cur
+	}
+
+	best := limit
+	for best >= 0 && !rows[len(sizes)][best] {
+		best--
+	}
+	if best < 0 {
+		return nil, ErrInfeasible
+	}
+
+	// Note: walking back, every size takes no packs when the sizes before it can do without, or the fewest
+	// steps that leave a total they can cover.
+	out := make(map[pack.ID]pack.Quantity)
+	for i, u := len(sizes)-1, best; i >= 0; i-- {
+		s := sizes[i]
+		if !s.Required && rows[i][u] {
+			continue
+		}
+		lo, hi, step := span(s, limit, opts)
+		for j := lo; j <= hi; j++ {
+			if u-j*step >= 0 && rows[i][u-j*step] {
+				out[s.ID] = pack.Quantity(j * max(int64(s.Multiple), 1))
+				u -= j * step
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// span is the range of counts of size a MostWithin row may take, in steps of its multiple: lo..hi steps of
+// step items each.
+func span(s pack.Size, limit int64, opts Options) (lo, hi, step int64) {
+	multiple := max(int64(s.Multiple), 1)
+	step = mulSat(s.Capacity, multiple)
+	if step <= 0 {
+		return 1, 0, 1
+	}
+
+	lo = max(ceilDiv(max(int64(s.MinQuantity), 1), multiple), 1)
+	hi = limit / step
+	if opts.Bounded {
+		hi = min(hi, int64(s.Stock)/multiple)
+	}
+	return lo, hi, step
+}
@@ -0,0 +1,100 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestMostWithin(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   pack.Sizes
+		limit   int64
+		bounded bool
+		want    int64
+		wantErr error
+	}{
+		{
+			name:    "all stock",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5, Stock: 2}, {ID: "L", Capacity: 8, Stock: 1}},
+			limit:   30,
+			bounded: true,
+			want:    18,
+		},
+		{
+			name:    "never over the limit",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5, Stock: 10}, {ID: "L", Capacity: 8, Stock: 10}},
+			limit:   12,
+			bounded: true,
+			want:    10,
+		},
+		{
+			name:    "exact",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5, Stock: 10}, {ID: "L", Capacity: 8, Stock: 10}},
+			limit:   21,
+			bounded: true,
+			want:    21,
+		},
+		{
+			name:    "multiple",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5, Stock: 3, Multiple: 2}},
+			limit:   100,
+			bounded: true,
+			want:    10,
+		},
+		{
+			name:    "minimum",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5, Stock: 10, MinQuantity: 3}, {ID: "L", Capacity: 8, Stock: 1}},
+			limit:   14,
+			bounded: true,
+			want:    8,
+		},
+		{
+			name:  "unbounded",
+			sizes: pack.Sizes{{ID: "S", Capacity: 5}, {ID: "L", Capacity: 8}},
+			limit: 12,
+			want:  10,
+		},
+		{
+			name:    "nothing on hand",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5}},
+			limit:   12,
+			bounded: true,
+			want:    0,
+		},
+		{
+			name:    "required out of stock",
+			sizes:   pack.Sizes{{ID: "S", Capacity: 5, Stock: 4}, {ID: "L", Capacity: 8, Required: true}},
+			limit:   12,
+			bounded: true,
+			wantErr: ErrInfeasible,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Bounded: tt.bounded}
+			got, err := MostWithin(context.Background(), tt.sizes, tt.limit, opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MostWithin() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v := VerifyOptions(tt.sizes, 0, got, opts); len(v) > 0 {
+				t.Errorf("MostWithin() = %v breaks %v", got, v)
+			}
+			items := int64(0)
+			for id, q := range got {
+				size, _ := tt.sizes.ByID(id)
+				items += int64(q) * size.Capacity
+			}
+			if items != tt.want {
+				t.Errorf("MostWithin() = %v covering %d, want %d", got, items, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Reallocate returns the allocation of demand that changes the fewest packs of current, counting every pack
+// added or removed, ranked by Rank among the ones changing as many. The allocation a finds from scratch
+// bounds the search, and its error is returned when it finds none. Both draw on the same budget, every count
+// tried is a cell of it.
+func Reallocate(ctx context.Context, a Allocator, sizes pack.Sizes, current map[pack.ID]pack.Quantity, demand int64, opts Options) (map[pack.ID]pack.Quantity, error) {
+	for id, q := range current {
+		if _, ok := sizes.ByID(id); !ok {
+			return nil, fmt.Errorf("%w: %s", pack.ErrUnknownSize, id)
+		}
+		if q < 0 {
+			return nil, fmt.Errorf("negative quantity %d of size %s", q, id)
+		}
+	}
+
+	opts.Meter = MeterFor(ctx, opts)
+	scratch, err := a.Allocate(ctx, sizes, demand, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newRealloc(sizes, current, demand, opts)
+	for i, s := range sizes {
+		r.best[i] = int64(scratch[s.ID])
+	}
+	r.bestChanges = Changes(current, scratch)
+	r.bestRank = RankOf(sizes, scratch, demand, opts)
+
+	if err := r.visit(0, 0, 0); err != nil {
+		return nil, err
+	}
+
+	out := make(map[pack.ID]pack.Quantity)
+	for i, k := range r.best {
+		if k > 0 {
+			out[sizes[i].ID] = pack.Quantity(k)
+		}
+	}
+	return out, nil
+}
+
+// Changes counts the packs added to and removed from before to get after.
+func Changes(before, after map[pack.ID]pack.Quantity) int64 {
+	out := int64(0)
+	for id, q := range after {
+		out += int64(max(q-before[id], 0))
+	}
+	for id, q := range before {
+		out += int64(max(q-after[id], 0))
+	}
+	return out
+}
+
+// realloc searches pack counts outwards from the current ones, the counts closest to them first.
+type realloc struct {
+	m       *Meter
+	sizes   pack.Sizes
+	current []int64
+	demand  int64
+	// most is the largest number of items within the tolerance, math.MaxInt64 when there is none.
+	most int64
+	opts Options
+
+	// kept are the items of sizes[i:] when their counts stay as they are, largest their largest capacity.
+	kept    []int64
+	largest []int64
+
+	counts      []int64
+	best        []int64
+	bestChanges int64
+	bestRank    Rank
+}
+
+func newRealloc(sizes pack.Sizes, current map[pack.ID]pack.Quantity, demand int64, opts Options) *realloc {
+	r := &realloc{
+		m:       opts.Meter,
+		sizes:   sizes,
+		current: make([]int64, len(sizes)),
+		demand:  demand,
+		most:    math.MaxInt64,
+		opts:    opts,
+		kept:    make([]int64, len(sizes)+1),
+		largest: make([]int64, len(sizes)+1),
+		counts:  make([]int64, len(sizes)),
+		best:    make([]int64, len(sizes)),
+	}
+	if allowed := opts.MaxOverfill.Allowed(demand); allowed >= 0 {
+		r.most = addSat(demand, allowed)
+	}
+	for i := len(sizes) - 1; i >= 0; i-- {
+		r.current[i] = int64(current[sizes[i].ID])
+		r.kept[i] = addSat(r.kept[i+1], mulSat(r.current[i], sizes[i].Capacity))
+		r.largest[i] = max(r.largest[i+1], sizes[i].Capacity)
+	}
+	return r
+}
+
+// visit branches on the count of sizes[i] after sizes[:i] covered units with changes packs changed.
+func (r *realloc) visit(i int, units, changes int64) error {
+	if err := r.m.Reserve(1); err != nil {
+		return err
+	}
+	if err := r.m.Tick(); err != nil {
+		return err
+	}
+
+	// Note: a lower bound on the changes left, each of them moves the items by at most the largest capacity.
+	kept := addSat(units, r.kept[i])
+	low := int64(0)
+	switch {
+	case kept < r.demand && r.largest[i] == 0, kept > r.most && r.largest[i] == 0:
+		return nil
+	case kept < r.demand:
+		low = ceilDiv(r.demand-kept, r.largest[i])
+	case kept > r.most:
+		low = ceilDiv(kept-r.most, r.largest[i])
+	}
+	if changes+low > r.bestChanges {
+		return nil
+	}
+
+	if i == len(r.sizes) {
+		r.consider(changes)
+		return nil
+	}
+
+	s := r.sizes[i]
+	limit := int64(math.MaxInt64)
+	if r.opts.Bounded {
+		limit = int64(s.Stock)
+	}
+
+	// Note: counts alternate around the current one, +0, +1, -1, +2, -2... until both directions run out.
+	up, down := true, true
+	for d := int64(0); (up || down) && changes+d <= r.bestChanges; d++ {
+		if up {
+			if k := r.current[i] + d; k > limit {
+				up = false
+			} else if err := r.try(i, k, units, changes+d); err != nil {
+				return err
+			}
+		}
+		if down && d > 0 {
+			if k := r.current[i] - d; k < 0 {
+				down = false
+			} else if err := r.try(i, k, units, changes+d); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// try takes k packs of sizes[i] when its constraints allow it.
+func (r *realloc) try(i int, k, units, changes int64) error {
+	s := r.sizes[i]
+	if !s.Allows(pack.Quantity(k)) {
+		return nil
+	}
+	r.counts[i] = k
+	err := r.visit(i+1, addSat(units, mulSat(k, s.Capacity)), changes)
+	r.counts[i] = 0
+	return err
+}
+
+// consider keeps the counts when they change fewer packs than the best ones, or as many ranking better.
+func (r *realloc) consider(changes int64) {
+	result := make(map[pack.ID]pack.Quantity)
+	for i, k := range r.counts {
+		result[r.sizes[i].ID] = pack.Quantity(k)
+	}
+	rank := RankOf(r.sizes, result, r.demand, r.opts)
+	if changes < r.bestChanges || (changes == r.bestChanges && rank.Less(r.bestRank)) {
+		copy(r.best, r.counts)
+		r.bestChanges = changes
+		r.bestRank = rank
+	}
+}
@@ -0,0 +1,188 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// bruteAllocator allocates by trying every combination of pack counts.
+type bruteAllocator struct{}
+
+func (bruteAllocator) Allocate(ctx context.Context, sizes pack.Sizes, demand int64, opts Options) (map[pack.ID]pack.Quantity, error) {
+	b := brute{
+		m:      MeterFor(ctx, opts),
+		sizes:  sizes,
+		demand: demand,
+		opts:   opts,
+		counts: make([]int64, len(sizes)),
+	}
+	if err := b.visit(0, 0); err != nil {
+		return nil, err
+	}
+	if b.best == nil {
+		return nil, ErrInfeasible
+	}
+	out := make(map[pack.ID]pack.Quantity)
+	for i, k := range b.best {
+		if k > 0 {
+			out[sizes[i].ID] = pack.Quantity(k)
+		}
+	}
+	return out, nil
+}
+
+func TestReallocate(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23, Stock: 10},
+		{ID: "L", Capacity: 31, Stock: 10},
+		{ID: "XL", Capacity: 53, Stock: 10},
+	}
+	current := map[pack.ID]pack.Quantity{"S": 1, "XL": 9}
+
+	tests := []struct {
+		name    string
+		demand  int64
+		opts    Options
+		want    map[pack.ID]pack.Quantity
+		wantErr error
+	}{
+		{name: "unchanged", demand: 500, want: current},
+		{name: "smaller demand keeps the packs", demand: 450, want: current},
+		{name: "add one pack", demand: 530, want: map[pack.ID]pack.Quantity{"S": 1, "L": 1, "XL": 9}},
+		{
+			name:   "remove one pack within tolerance",
+			demand: 447,
+			opts:   Options{MaxOverfill: Tolerance{Limited: true}},
+			want:   map[pack.ID]pack.Quantity{"S": 1, "XL": 8},
+		},
+		{
+			name:   "add two packs",
+			demand: 560,
+			opts:   Options{Bounded: true},
+			want:   map[pack.ID]pack.Quantity{"S": 1, "L": 2, "XL": 9},
+		},
+		{name: "infeasible", demand: 2000, opts: Options{Bounded: true}, wantErr: ErrInfeasible},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reallocate(context.Background(), bruteAllocator{}, sizes, current, tt.demand, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reallocate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("Reallocate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := Reallocate(context.Background(), bruteAllocator{}, sizes, map[pack.ID]pack.Quantity{"XXL": 1}, 10, Options{})
+	if !errors.Is(err, pack.ErrUnknownSize) {
+		t.Errorf("Reallocate() error = %v, want %v", err, pack.ErrUnknownSize)
+	}
+}
+
+func TestReallocate_Budget(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 23},
+		{ID: "L", Capacity: 31},
+		{ID: "XL", Capacity: 53},
+	}
+	current := map[pack.ID]pack.Quantity{"S": 1, "XL": 9}
+
+	// Note: the search and the allocation from scratch draw on the same meter, each count tried is a cell.
+	scratch := NewMeter(context.Background(), Budget{})
+	if _, err := (bruteAllocator{}).Allocate(context.Background(), sizes, 530, Options{Meter: scratch}); err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+
+	m := NewMeter(context.Background(), Budget{})
+	if _, err := Reallocate(context.Background(), bruteAllocator{}, sizes, current, 530, Options{Meter: m}); err != nil {
+		t.Fatalf("Reallocate() error = %v", err)
+	}
+	if m.cells <= scratch.cells {
+		t.Errorf("Reallocate() charged %d cells, want more than the %d of the allocation from scratch", m.cells, scratch.cells)
+	}
+
+	_, err := Reallocate(context.Background(), bruteAllocator{}, sizes, current, 530, Options{Budget: Budget{MaxCells: m.cells - 1}})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Reallocate() error = %v, want %v", err, ErrBudgetExceeded)
+	}
+}
+
+func TestReallocate_BruteForce(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "A", Capacity: 3, Price: 4, Stock: 6},
+		{ID: "B", Capacity: 5, Price: 6, MinQuantity: 2, Stock: 6},
+		{ID: "C", Capacity: 7, Price: 9, Multiple: 2, Stock: 6},
+	}
+	currents := []map[pack.ID]pack.Quantity{
+		{},
+		{"A": 2},
+		{"A": 1, "B": 3},
+		{"B": 2, "C": 4},
+		{"A": 6, "B": 6, "C": 6},
+	}
+	options := []struct {
+		name string
+		opts Options
+	}{
+		{name: "unbounded"},
+		{name: "bounded", opts: Options{Bounded: true}},
+		{name: "cheapest", opts: Options{Objective: MinCost}},
+		{name: "within 1", opts: Options{MaxOverfill: Tolerance{Limited: true, Units: 1}}},
+	}
+
+	for _, current := range currents {
+		for _, o := range options {
+			opts := o.opts
+			t.Run(fmt.Sprintf("%v/%s", current, o.name), func(t *testing.T) {
+				for demand := int64(1); demand <= 60; demand++ {
+					want, wantChanges, ok := bruteRealloc(sizes, current, demand, opts)
+					got, err := Reallocate(context.Background(), bruteAllocator{}, sizes, current, demand, opts)
+					if !ok {
+						if err == nil {
+							t.Fatalf("demand %d: Reallocate() = %v, want error", demand, got)
+						}
+						continue
+					}
+					if err != nil {
+						t.Fatalf("demand %d: Reallocate() error = %v", demand, err)
+					}
+					if c := Changes(current, got); c != wantChanges || RankOf(sizes, got, demand, opts) != RankOf(sizes, want, demand, opts) {
+						t.Fatalf("demand %d: Reallocate() = %v changing %d, want %v changing %d", demand, got, c, want, wantChanges)
+					}
+				}
+			})
+		}
+	}
+}
+
+// bruteRealloc tries every count of every size up to 12 packs.
+func bruteRealloc(sizes pack.Sizes, current map[pack.ID]pack.Quantity, demand int64, opts Options) (map[pack.ID]pack.Quantity, int64, bool) {
+	var (
+		best        map[pack.ID]pack.Quantity
+		bestChanges int64
+		bestRank    Rank
+	)
+	for a := pack.Quantity(0); a <= 12; a++ {
+		for b := pack.Quantity(0); b <= 12; b++ {
+			for c := pack.Quantity(0); c <= 12; c++ {
+				cand := map[pack.ID]pack.Quantity{"A": a, "B": b, "C": c}
+				if len(VerifyOptions(sizes, demand, cand, opts)) > 0 {
+					continue
+				}
+				changes, rank := Changes(current, cand), RankOf(sizes, cand, demand, opts)
+				if best == nil || changes < bestChanges || (changes == bestChanges && rank.Less(bestRank)) {
+					best, bestChanges, bestRank = cand, changes, rank
+				}
+			}
+		}
+	}
+	return best, bestChanges, best != nil
+}
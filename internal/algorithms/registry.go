@@ -0,0 +1,70 @@
+package algorithms
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrUnknownAlgorithm is returned when no allocator is registered under a name.
+var ErrUnknownAlgorithm = errors.New("unknown algorithm")
+
+// Registry names the allocators a service can choose from. Allocators are registered at startup,
+// lookups are safe for concurrent use afterwards.
+type Registry struct {
+	allocators map[string]Allocator
+	fallback   string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		allocators: make(map[string]Allocator),
+	}
+}
+
+// Register adds an allocator under name, the first one registered becomes the default.
+// It panics when the name is empty or already taken.
+func (r *Registry) Register(name string, a Allocator) {
+	if name == "" {
+		panic("algorithms: registering an allocator without a name")
+	}
+	if _, ok := r.allocators[name]; ok {
+		panic("algorithms: allocator " + name + " registered twice")
+	}
+
+	r.allocators[name] = a
+	if r.fallback == "" {
+		r.fallback = name
+	}
+}
+
+// SetDefault picks the allocator Lookup returns for an empty name.
+func (r *Registry) SetDefault(name string) error {
+	if _, ok := r.allocators[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownAlgorithm, name)
+	}
+	r.fallback = name
+	return nil
+}
+
+func (r *Registry) Default() string {
+	return r.fallback
+}
+
+// Lookup returns the allocator registered under name along with that name, the default one for an empty name.
+func (r *Registry) Lookup(name string) (string, Allocator, error) {
+	if name == "" {
+		name = r.fallback
+	}
+	a, ok := r.allocators[name]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, name)
+	}
+	return name, a, nil
+}
+
+// Names lists the registered allocators in alphabetical order.
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.allocators))
+}
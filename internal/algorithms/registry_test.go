@@ -0,0 +1,65 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+type named string
+
+func (n named) Allocate(context.Context, pack.Sizes, int64, Options) (map[pack.ID]pack.Quantity, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if _, _, err := r.Lookup(""); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Fatalf("Lookup() on empty registry error = %v, want %v", err, ErrUnknownAlgorithm)
+	}
+
+	r.Register("dp", named("dp"))
+	r.Register("bnb", named("bnb"))
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "", want: "dp"},
+		{name: "dp", want: "dp"},
+		{name: "bnb", want: "bnb"},
+		{name: "simplex", wantErr: ErrUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, a, err := r.Lookup(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() name = %q, want %q", got, tt.want)
+			}
+			if a != nil && a != named(tt.want) {
+				t.Errorf("Lookup() allocator = %v, want %v", a, tt.want)
+			}
+		})
+	}
+
+	if err := r.SetDefault("bnb"); err != nil {
+		t.Fatalf("SetDefault() error = %v", err)
+	}
+	if got, _, _ := r.Lookup(""); got != "bnb" {
+		t.Errorf("Lookup() after SetDefault = %q, want %q", got, "bnb")
+	}
+	if err := r.SetDefault("simplex"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("SetDefault() error = %v, want %v", err, ErrUnknownAlgorithm)
+	}
+	if got := r.Names(); !slices.Equal(got, []string{"bnb", "dp"}) {
+		t.Errorf("Names() = %v, want [bnb dp]", got)
+	}
+}
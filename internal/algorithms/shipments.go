@@ -0,0 +1,351 @@
+package algorithms
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// ErrOverLimit is returned when a single pack is heavier or larger than a whole shipment may be.
+var ErrOverLimit = errors.New("pack exceeds the shipment limits")
+
+// ShipmentLimits caps the totals of a single shipment, zero leaves a measure unlimited.
+type ShipmentLimits struct {
+	// MaxWeight is in grams.
+	MaxWeight int64 `json:"max_weight"`
+	// MaxVolume is in cubic centimetres.
+	MaxVolume int64 `json:"max_volume"`
+}
+
+// Limited reports whether the limits cap anything.
+func (l ShipmentLimits) Limited() bool {
+	return l.MaxWeight > 0 || l.MaxVolume > 0
+}
+
+// Fits reports whether a single pack of size is within the limits.
+func (l ShipmentLimits) Fits(size pack.Size) bool {
+	return l.fits(size.Weight, size.Volume())
+}
+
+// fits reports whether a shipment of weight w and volume v is within the limits.
+func (l ShipmentLimits) fits(w, v int64) bool {
+	return (l.MaxWeight <= 0 || w <= l.MaxWeight) && (l.MaxVolume <= 0 || v <= l.MaxVolume)
+}
+
+// Ship splits the packs of an allocation into the fewest shipments within limits, heaviest and largest
+// packs first. It reports whether the number of shipments is proven to be the fewest for these packs: first
+// fit decreasing is improved on by an exhaustive search that stops when it runs out of budget. Other packs
+// covering the same demand are not considered, ShipFewest tries them as well. Only the budget of opts applies.
+func Ship(ctx context.Context, sizes pack.Sizes, packs map[pack.ID]pack.Quantity, limits ShipmentLimits, opts Options) ([]map[pack.ID]pack.Quantity, bool, error) {
+	s := shipper{m: MeterFor(ctx, opts), limits: limits}
+
+	for id := range packs {
+		if _, ok := sizes.ByID(id); !ok {
+			return nil, false, fmt.Errorf("%w: %s", pack.ErrUnknownSize, id)
+		}
+	}
+
+	// Note: sizes are iterated rather than packs so that the order of the items is deterministic.
+	var totalW, totalV int64
+	for _, size := range sizes {
+		q := int64(packs[size.ID])
+		if q <= 0 {
+			continue
+		}
+		if !limits.fits(size.Weight, size.Volume()) {
+			return nil, false, fmt.Errorf("%w: size %s weighs %d g in %d cm3", ErrOverLimit, size.ID, size.Weight, size.Volume())
+		}
+		if err := s.m.Reserve(q); err != nil {
+			return nil, false, err
+		}
+		for range q {
+			s.items = append(s.items, size)
+		}
+		totalW = addSat(totalW, mulSat(q, size.Weight))
+		totalV = addSat(totalV, mulSat(q, size.Volume()))
+	}
+	if len(s.items) == 0 {
+		return nil, true, nil
+	}
+
+	slices.SortStableFunc(s.items, func(a, b pack.Size) int {
+		return cmp.Or(cmp.Compare(s.share(b), s.share(a)), cmp.Compare(a.ID, b.ID))
+	})
+
+	low := int64(1)
+	if limits.MaxWeight > 0 {
+		low = max(low, ceilDiv(totalW, limits.MaxWeight))
+	}
+	if limits.MaxVolume > 0 {
+		low = max(low, ceilDiv(totalV, limits.MaxVolume))
+	}
+
+	best, shipments, err := s.firstFit()
+	if err != nil {
+		return nil, false, err
+	}
+	for k := int(low); k < shipments; k++ {
+		found, err := s.search(k)
+		if errors.Is(err, ErrBudgetExceeded) {
+			return s.result(best), false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if found != nil {
+			return s.result(found), true, nil
+		}
+	}
+	return s.result(best), true, nil
+}
+
+type shipper struct {
+	m      *Meter
+	limits ShipmentLimits
+	// items holds one size per pack, the ones taking the largest share of a shipment first.
+	items []pack.Size
+}
+
+// share is the larger fraction of a shipment one pack of size takes, used to order the packs.
+func (s *shipper) share(size pack.Size) float64 {
+	out := 0.0
+	if s.limits.MaxWeight > 0 {
+		out = float64(size.Weight) / float64(s.limits.MaxWeight)
+	}
+	if s.limits.MaxVolume > 0 {
+		out = max(out, float64(size.Volume())/float64(s.limits.MaxVolume))
+	}
+	return out
+}
+
+// load is what a shipment holds so far.
+type load struct {
+	weight, volume int64
+}
+
+// firstFit puts every pack in the first shipment it fits in, returning the shipment of each pack and
+// how many shipments it opened. Every shipment looked at is a tick of the budget.
+func (s *shipper) firstFit() ([]int, int, error) {
+	var loads []load
+	assigned := make([]int, len(s.items))
+	for i, item := range s.items {
+		j := 0
+		for ; j < len(loads); j++ {
+			if err := s.m.Tick(); err != nil {
+				return nil, 0, err
+			}
+			if s.limits.fits(loads[j].weight+item.Weight, loads[j].volume+item.Volume()) {
+				break
+			}
+		}
+		if j == len(loads) {
+			loads = append(loads, load{})
+		}
+		loads[j].weight += item.Weight
+		loads[j].volume += item.Volume()
+		assigned[i] = j
+	}
+	return assigned, len(loads), nil
+}
+
+// search looks for a way to ship the packs in k shipments, returning the shipment of each pack or nil.
+func (s *shipper) search(k int) ([]int, error) {
+	loads := make([]load, k)
+	assigned := make([]int, len(s.items))
+
+	var visit func(i int) (bool, error)
+	visit = func(i int) (bool, error) {
+		if i == len(s.items) {
+			return true, nil
+		}
+		if err := s.m.Reserve(1); err != nil {
+			return false, err
+		}
+		if err := s.m.Tick(); err != nil {
+			return false, err
+		}
+
+		item := s.items[i]
+		first := 0
+		// Note: packs of the same size are interchangeable, so each goes in the same shipment as the one
+		// before it or a later one.
+		if i > 0 && s.items[i-1].ID == item.ID {
+			first = assigned[i-1]
+		}
+		for j := first; j < k; j++ {
+			// Note: shipments holding the same load are interchangeable too, only the first of them is tried.
+			if slices.Contains(loads[first:j], loads[j]) {
+				continue
+			}
+			if !s.limits.fits(loads[j].weight+item.Weight, loads[j].volume+item.Volume()) {
+				continue
+			}
+
+			loads[j].weight += item.Weight
+			loads[j].volume += item.Volume()
+			assigned[i] = j
+			ok, err := visit(i + 1)
+			loads[j].weight -= item.Weight
+			loads[j].volume -= item.Volume()
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	ok, err := visit(0)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// result groups the packs by the shipment they were assigned to, in the order the shipments were opened.
+func (s *shipper) result(assigned []int) []map[pack.ID]pack.Quantity {
+	var out []map[pack.ID]pack.Quantity
+	for i, j := range assigned {
+		for len(out) <= j {
+			out = append(out, make(map[pack.ID]pack.Quantity))
+		}
+		out[j][s.items[i].ID]++
+	}
+	out = slices.DeleteFunc(out, func(m map[pack.ID]pack.Quantity) bool { return len(m) == 0 })
+	return out
+}
+
+// ShipFewest splits into the fewest shipments within limits any allocation tied with packs: holding as many
+// items and, for MinCost, costing as much, so both rank the same on the objective and overfill. Ties on
+// shipments go to the allocation ranking best, packs first. It returns the allocation with its shipments and
+// reports whether none of the tied allocations ships in fewer: all of them were tried and every split proven.
+//
+// Sizes over the limits are never tried. Each allocation tried is a cell of the budget of opts, splitting it
+// draws on the budget as well, and running out keeps the best allocation so far.
+func ShipFewest(ctx context.Context, sizes pack.Sizes, packs map[pack.ID]pack.Quantity, limits ShipmentLimits, opts Options) (map[pack.ID]pack.Quantity, []map[pack.ID]pack.Quantity, bool, error) {
+	opts.Meter = MeterFor(ctx, opts)
+
+	split, proven, err := Ship(ctx, sizes, packs, limits, opts)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	want := measure(sizes, packs)
+	t := tied{
+		m:      opts.Meter,
+		ctx:    ctx,
+		sizes:  sizes,
+		limits: limits,
+		opts:   opts,
+		items:  want[1],
+		cost:   -1,
+		counts: make([]int64, len(sizes)),
+		packs:  packs,
+		best:   packs,
+		split:  split,
+		rank:   RankOf(sizes, packs, 0, opts),
+		proven: proven,
+	}
+	if opts.Objective == MinCost {
+		t.cost = want[0]
+	}
+
+	err = t.visit(0, 0, 0)
+	switch {
+	case errors.Is(err, ErrBudgetExceeded):
+		t.proven = false
+	case err != nil:
+		return nil, nil, false, err
+	}
+	return t.best, t.split, t.proven, nil
+}
+
+// tied searches the allocations holding items items, costing cost unless it is negative, for the one shipping
+// in the fewest shipments.
+type tied struct {
+	m      *Meter
+	ctx    context.Context
+	sizes  pack.Sizes
+	limits ShipmentLimits
+	opts   Options
+	items  int64
+	cost   int64
+
+	counts []int64
+	packs  map[pack.ID]pack.Quantity
+
+	best   map[pack.ID]pack.Quantity
+	split  []map[pack.ID]pack.Quantity
+	rank   Rank
+	proven bool
+}
+
+func (t *tied) visit(i int, units, cost int64) error {
+	if err := t.m.Tick(); err != nil {
+		return err
+	}
+	if t.cost >= 0 && cost > t.cost {
+		return nil
+	}
+	if i == len(t.sizes) {
+		if units != t.items || t.cost >= 0 && cost != t.cost {
+			return nil
+		}
+		return t.leaf()
+	}
+
+	s := t.sizes[i]
+	top := (t.items - units) / s.Capacity
+	if !t.limits.Fits(s) {
+		top = 0
+	}
+	if t.opts.Bounded {
+		top = min(top, int64(s.Stock))
+	}
+	for k := int64(0); k <= top; k++ {
+		if !s.Allows(pack.Quantity(k)) {
+			continue
+		}
+		t.counts[i] = k
+		err := t.visit(i+1, units+k*s.Capacity, addSat(cost, mulSat(k, s.Price)))
+		t.counts[i] = 0
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leaf splits the allocation of the current counts and keeps it when it ships in fewer shipments.
+func (t *tied) leaf() error {
+	if err := t.m.Reserve(1); err != nil {
+		return err
+	}
+
+	dist := make(map[pack.ID]pack.Quantity)
+	same := true
+	for i, k := range t.counts {
+		if k > 0 {
+			dist[t.sizes[i].ID] = pack.Quantity(k)
+		}
+		same = same && pack.Quantity(k) == t.packs[t.sizes[i].ID]
+	}
+	if same {
+		return nil
+	}
+
+	split, proven, err := Ship(t.ctx, t.sizes, dist, t.limits, t.opts)
+	if err != nil {
+		return err
+	}
+	t.proven = t.proven && proven
+
+	rank := RankOf(t.sizes, dist, 0, t.opts)
+	if len(split) < len(t.split) || len(split) == len(t.split) && rank.Less(t.rank) {
+		t.best, t.split, t.rank = dist, split, rank
+	}
+	return nil
+}
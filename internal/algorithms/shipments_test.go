@@ -0,0 +1,122 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestShip(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "A", Capacity: 50, Weight: 5, Dimensions: pack.Dimensions{Length: 1, Width: 1, Height: 5}},
+		{ID: "B", Capacity: 40, Weight: 4, Dimensions: pack.Dimensions{Length: 1, Width: 1, Height: 1}},
+		{ID: "C", Capacity: 30, Weight: 3, Dimensions: pack.Dimensions{Length: 1, Width: 1, Height: 1}},
+		{ID: "D", Capacity: 20, Weight: 2, Dimensions: pack.Dimensions{Length: 1, Width: 1, Height: 2}},
+	}
+	// Note: first fit decreasing needs three shipments of 10 g for these, {A, C, D} and {B, B, D} take two.
+	packs := map[pack.ID]pack.Quantity{"A": 1, "B": 2, "C": 1, "D": 2}
+
+	tests := []struct {
+		name      string
+		packs     map[pack.ID]pack.Quantity
+		limits    ShipmentLimits
+		budget    Budget
+		want      int
+		wantProof bool
+		wantErr   error
+	}{
+		{name: "unlimited", packs: packs, want: 1, wantProof: true},
+		{name: "improves on first fit", packs: packs, limits: ShipmentLimits{MaxWeight: 10}, want: 2, wantProof: true},
+		{name: "volume", packs: packs, limits: ShipmentLimits{MaxVolume: 5}, want: 3, wantProof: true},
+		{name: "both", packs: packs, limits: ShipmentLimits{MaxWeight: 10, MaxVolume: 8}, want: 2, wantProof: true},
+		{name: "over budget keeps first fit", packs: packs, limits: ShipmentLimits{MaxWeight: 10}, budget: Budget{MaxCells: 7}, want: 3},
+		{name: "no packs", packs: map[pack.ID]pack.Quantity{}, limits: ShipmentLimits{MaxWeight: 10}, wantProof: true},
+		{name: "pack over the limit", packs: packs, limits: ShipmentLimits{MaxWeight: 4}, wantErr: ErrOverLimit},
+		{name: "unknown size", packs: map[pack.ID]pack.Quantity{"X": 1}, wantErr: pack.ErrUnknownSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, proven, err := Ship(context.Background(), sizes, tt.packs, tt.limits, Options{Budget: tt.budget})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Ship() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != tt.want || proven != tt.wantProof {
+				t.Errorf("Ship() = %v proven %v, want %d shipments proven %v", got, proven, tt.want, tt.wantProof)
+			}
+
+			total := make(map[pack.ID]pack.Quantity)
+			for _, shipment := range got {
+				var w, v int64
+				for id, q := range shipment {
+					size, _ := sizes.ByID(id)
+					w += int64(q) * size.Weight
+					v += int64(q) * size.Volume()
+					total[id] += q
+				}
+				if !tt.limits.fits(w, v) {
+					t.Errorf("shipment %v of %d g in %d cm3 breaks %+v", shipment, w, v, tt.limits)
+				}
+			}
+			for id, q := range tt.packs {
+				if total[id] != q {
+					t.Errorf("Ship() shipped %d of %s, want %d", total[id], id, q)
+				}
+			}
+		})
+	}
+	// Note: one pack per shipment makes first fit look at every shipment opened so far, it has to stop.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := Ship(ctx, sizes, map[pack.ID]pack.Quantity{"A": 10000}, ShipmentLimits{MaxWeight: 5}, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Ship() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestShipFewest(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "A", Capacity: 10, Weight: 5, Price: 10},
+		{ID: "B", Capacity: 5, Weight: 1, Price: 6},
+		{ID: "C", Capacity: 20, Weight: 9, Price: 1},
+	}
+	limits := ShipmentLimits{MaxWeight: 5}
+
+	tests := []struct {
+		name      string
+		packs     map[pack.ID]pack.Quantity
+		opts      Options
+		want      map[pack.ID]pack.Quantity
+		wantShips int
+		wantProof bool
+		wantErr   error
+	}{
+		// Note: 2×A ships one pack a shipment, 4×B holds as many items in a single one. C is over the limit.
+		{name: "fewer shipments with the same overfill", packs: map[pack.ID]pack.Quantity{"A": 2}, want: map[pack.ID]pack.Quantity{"B": 4}, wantShips: 1, wantProof: true},
+		{name: "keeps the allocation on ties", packs: map[pack.ID]pack.Quantity{"A": 1}, want: map[pack.ID]pack.Quantity{"A": 1}, wantShips: 1, wantProof: true},
+		{name: "cost ties only", packs: map[pack.ID]pack.Quantity{"A": 2}, opts: Options{Objective: MinCost}, want: map[pack.ID]pack.Quantity{"A": 2}, wantShips: 2, wantProof: true},
+		{name: "stock", packs: map[pack.ID]pack.Quantity{"A": 2}, opts: Options{Bounded: true}, want: map[pack.ID]pack.Quantity{"A": 2}, wantShips: 2, wantProof: true},
+		{name: "over budget", packs: map[pack.ID]pack.Quantity{"A": 2}, opts: Options{Budget: Budget{MaxCells: 3}}, want: map[pack.ID]pack.Quantity{"A": 2}, wantShips: 2},
+		{name: "pack over the limit", packs: map[pack.ID]pack.Quantity{"C": 1}, wantErr: ErrOverLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, split, proven, err := ShipFewest(context.Background(), sizes, tt.packs, limits, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ShipFewest() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !equalPacks(got, tt.want) || len(split) != tt.wantShips || proven != tt.wantProof {
+				t.Errorf("ShipFewest() = %v in %d shipments proven %v, want %v in %d proven %v", got, len(split), proven, tt.want, tt.wantShips, tt.wantProof)
+			}
+		})
+	}
+}
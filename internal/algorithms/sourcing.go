@@ -0,0 +1,203 @@
+package algorithms
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Sourcing is an allocation split between the locations its packs are taken from.
+type Sourcing struct {
+	// Packs maps every location used to the packs taken from it.
+	Packs map[string]map[pack.ID]pack.Quantity
+	// Cost is the price of the packs plus the shipping cost of the locations used.
+	Cost int64
+	// Proven is set when no split ranks better, the allocator is exact and the search finished in budget.
+	Proven bool
+}
+
+// MaxSourcingLocations caps the stocked locations Source enumerates the sets of.
+const MaxSourcingLocations = 16
+
+// Source allocates demand from the stock of the locations for the lowest cost, counting the price of the
+// packs and the shipping cost of every location used, then the fewest locations, the smallest overfill and
+// the fewest packs. Every set of locations is tried by allocating with a for the lowest price from their stock
+// combined, the cheapest sets first, until the shipping cost alone rules out the rest. Each set counts as a
+// cell of the budget, the allocations draw on it as well. More than MaxSourcingLocations stocked locations
+// are not supported.
+func Source(ctx context.Context, a Allocator, sizes pack.Sizes, locations pack.Locations, demand int64, opts Options) (Sourcing, error) {
+	m := MeterFor(ctx, opts)
+	opts.Objective = MinCost
+	opts.Bounded = true
+
+	// Note: locations without any stock can only add shipping, so they are left out.
+	locations = slices.DeleteFunc(slices.Clone(locations), func(l pack.Location) bool {
+		return stockedItems(l.Sizes(sizes)) == 0
+	})
+	if len(locations) == 0 {
+		return Sourcing{}, ErrInsufficientStock
+	}
+	if len(locations) > MaxSourcingLocations {
+		return Sourcing{}, fmt.Errorf("sourcing from %d locations, at most %d: %w", len(locations), MaxSourcingLocations, errors.ErrUnsupported)
+	}
+	if err := m.Reserve(int64(1) << len(locations)); err != nil {
+		return Sourcing{}, err
+	}
+
+	exact := false
+	if e, ok := a.(ExactAllocator); ok {
+		exact = e.Exact()
+	}
+
+	// Note: the cheapest packs ignoring stock bound the price of the packs any set of locations can offer.
+	low := int64(0)
+	if exact {
+		unbounded := opts
+		unbounded.Bounded = false
+		unbounded.Meter = m
+		dist, err := a.Allocate(ctx, sizes, demand, unbounded)
+		if err != nil {
+			return Sourcing{}, err
+		}
+		low = measure(sizes, dist)[0]
+	}
+
+	sets := make([]uint64, 0, 1<<len(locations)-1)
+	for set := uint64(1); set < 1<<len(locations); set++ {
+		sets = append(sets, set)
+	}
+	shipping := func(set uint64) int64 {
+		out := int64(0)
+		for i, l := range locations {
+			if set&(1<<i) != 0 {
+				out = addSat(out, l.ShippingCost)
+			}
+		}
+		return out
+	}
+	slices.SortFunc(sets, func(x, y uint64) int {
+		return cmp.Or(cmp.Compare(shipping(x), shipping(y)), cmp.Compare(bits.OnesCount64(x), bits.OnesCount64(y)))
+	})
+
+	var (
+		best     Sourcing
+		bestRank [4]int64
+		found    bool
+		last     error = ErrInsufficientStock
+	)
+	for _, set := range sets {
+		if err := m.Check(); err != nil {
+			return timedOut(best, found, err)
+		}
+
+		ship, count := shipping(set), int64(bits.OnesCount64(set))
+		if exact && found && (addSat(ship, low) > bestRank[0] || addSat(ship, low) == bestRank[0] && count > bestRank[1]) {
+			break
+		}
+
+		var picked pack.Locations
+		for i, l := range locations {
+			if set&(1<<i) != 0 {
+				picked = append(picked, l)
+			}
+		}
+		combined := combine(sizes, picked)
+		if stockedItems(combined) < demand {
+			continue
+		}
+
+		inner := opts
+		inner.Meter = m
+		dist, err := a.Allocate(ctx, combined, demand, inner)
+		if errors.Is(err, ErrInfeasible) || errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrOutOfTolerance) {
+			last = err
+			continue
+		}
+		if err != nil {
+			return timedOut(best, found, err)
+		}
+
+		split := splitStock(sizes, picked, dist)
+		totals := measure(sizes, dist)
+		cost := totals[0]
+		for _, l := range picked {
+			if _, ok := split[l.ID]; ok {
+				cost = addSat(cost, l.ShippingCost)
+			}
+		}
+		rank := [4]int64{cost, int64(len(split)), totals[1] - demand, totals[2]}
+		if !found || slices.Compare(rank[:], bestRank[:]) < 0 {
+			best, bestRank, found = Sourcing{Packs: split, Cost: cost}, rank, true
+		}
+	}
+
+	if !found {
+		return Sourcing{}, last
+	}
+	best.Proven = exact
+	return best, nil
+}
+
+// timedOut keeps the best split found so far when the search runs out of budget.
+func timedOut(best Sourcing, found bool, err error) (Sourcing, error) {
+	if found && errors.Is(err, ErrBudgetExceeded) {
+		return best, nil
+	}
+	return Sourcing{}, err
+}
+
+// combine returns the sizes with the stock of the locations added up.
+func combine(sizes pack.Sizes, locations pack.Locations) pack.Sizes {
+	out := make(pack.Sizes, len(sizes))
+	for i, s := range sizes {
+		s.Stock = 0
+		for _, l := range locations {
+			s.Stock += l.Stock[s.ID]
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// splitStock takes the packs of every size from the locations in order, the cheapest to ship first, each
+// giving all it stocks before the next one is used.
+func splitStock(sizes pack.Sizes, locations pack.Locations, dist map[pack.ID]pack.Quantity) map[string]map[pack.ID]pack.Quantity {
+	locations = slices.Clone(locations)
+	slices.SortStableFunc(locations, func(x, y pack.Location) int {
+		return cmp.Compare(x.ShippingCost, y.ShippingCost)
+	})
+
+	out := make(map[string]map[pack.ID]pack.Quantity)
+	for _, s := range sizes {
+		left := dist[s.ID]
+		for _, l := range locations {
+			if left <= 0 {
+				break
+			}
+			take := min(left, l.Stock[s.ID])
+			if take <= 0 {
+				continue
+			}
+			if out[l.ID] == nil {
+				out[l.ID] = make(map[pack.ID]pack.Quantity)
+			}
+			out[l.ID][s.ID] = take
+			left -= take
+		}
+	}
+	return out
+}
+
+// stockedItems is the number of items the stock of the sizes holds.
+func stockedItems(sizes pack.Sizes) int64 {
+	out := int64(0)
+	for _, s := range sizes {
+		out = addSat(out, mulSat(s.Capacity, int64(max(s.Stock, 0))))
+	}
+	return out
+}
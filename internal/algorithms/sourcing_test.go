@@ -0,0 +1,93 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// exactBrute is a bruteAllocator known to be exact, letting Source prune.
+type exactBrute struct {
+	bruteAllocator
+}
+
+func (exactBrute) Exact() bool { return true }
+
+func TestSource(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 5, Price: 10},
+		{ID: "L", Capacity: 8, Price: 12},
+	}
+	locations := pack.Locations{
+		{ID: "A", ShippingCost: 100, Stock: map[pack.ID]pack.Quantity{"S": 10}},
+		{ID: "B", ShippingCost: 30, Stock: map[pack.ID]pack.Quantity{"L": 1}},
+		{ID: "C", ShippingCost: 50, Stock: map[pack.ID]pack.Quantity{"S": 2, "L": 5}},
+	}
+	// Note: three locations of the same shipping cost, one of them covering alone what the other two do together.
+	twins := pack.Locations{
+		{ID: "D", Stock: map[pack.ID]pack.Quantity{"S": 1}},
+		{ID: "E", Stock: map[pack.ID]pack.Quantity{"S": 1}},
+		{ID: "F", Stock: map[pack.ID]pack.Quantity{"S": 2}},
+	}
+	many := make(pack.Locations, MaxSourcingLocations+1)
+	for i := range many {
+		many[i] = pack.Location{ID: fmt.Sprint(i), Stock: map[pack.ID]pack.Quantity{"S": 1}}
+	}
+
+	tests := []struct {
+		name      string
+		a         Allocator
+		locations pack.Locations
+		demand    int64
+		wantCost  int64
+		wantFrom  []string
+		wantProof bool
+		wantErr   error
+	}{
+		{name: "one location", a: exactBrute{}, locations: locations, demand: 16, wantCost: 74, wantFrom: []string{"C"}, wantProof: true},
+		{name: "cheapest packs", a: exactBrute{}, locations: locations, demand: 40, wantCost: 110, wantFrom: []string{"C"}, wantProof: true},
+		{name: "two locations", a: exactBrute{}, locations: locations, demand: 60, wantCost: 250, wantFrom: []string{"A", "C"}, wantProof: true},
+		{name: "fewest locations", a: exactBrute{}, locations: twins, demand: 10, wantCost: 20, wantFrom: []string{"F"}, wantProof: true},
+		{name: "not exact", a: bruteAllocator{}, locations: locations, demand: 60, wantCost: 250, wantFrom: []string{"A", "C"}},
+		{name: "insufficient stock", a: exactBrute{}, locations: locations, demand: 1000, wantErr: ErrInsufficientStock},
+		{name: "no locations", a: exactBrute{}, demand: 10, wantErr: ErrInsufficientStock},
+		{name: "too many locations", a: exactBrute{}, locations: many, demand: 10, wantErr: errors.ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source(context.Background(), tt.a, sizes, tt.locations, tt.demand, Options{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Source() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			from := slices.Sorted(maps.Keys(got.Packs))
+			if got.Cost != tt.wantCost || !slices.Equal(from, tt.wantFrom) || got.Proven != tt.wantProof {
+				t.Errorf("Source() = %v costing %d proven %v, want %v costing %d proven %v", got.Packs, got.Cost, got.Proven, tt.wantFrom, tt.wantCost, tt.wantProof)
+			}
+
+			items := int64(0)
+			for id, packs := range got.Packs {
+				loc, _ := tt.locations.ByID(id)
+				for size, q := range packs {
+					if q > loc.Stock[size] {
+						t.Errorf("Source() takes %d of %s from %s stocking %d", q, size, id, loc.Stock[size])
+					}
+					s, _ := sizes.ByID(size)
+					items += int64(q) * s.Capacity
+				}
+			}
+			if items < tt.demand {
+				t.Errorf("Source() covers %d items, want at least %d", items, tt.demand)
+			}
+		})
+	}
+}
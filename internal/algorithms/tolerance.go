@@ -0,0 +1,126 @@
+package algorithms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrOutOfTolerance is matched by every ToleranceError.
+var ErrOutOfTolerance = errors.New("no allocation within tolerance")
+
+// ToleranceError reports the closest overfill achievable when it exceeds the tolerance.
+type ToleranceError struct {
+	// Allowed is the most overfill the tolerance accepts for the demand.
+	Allowed int64
+	// Closest is the smallest overfill any allocation reaches.
+	Closest int64
+}
+
+func (e *ToleranceError) Error() string {
+	return fmt.Sprintf("%s: overfill of at most %d allowed, closest achievable is %d", ErrOutOfTolerance, e.Allowed, e.Closest)
+}
+
+func (e *ToleranceError) Unwrap() error {
+	return ErrOutOfTolerance
+}
+
+// Tolerance caps the overfill of an allocation, the zero value accepts any overfill.
+type Tolerance struct {
+	// Limited turns the cap on, a limited tolerance of zero only accepts exact fills.
+	Limited bool
+	// Units caps the overfill in items.
+	Units int64
+	// BasisPoints caps the overfill relative to the demand, in hundredths of a percent.
+	// When both caps are set the larger one applies.
+	BasisPoints int64
+}
+
+// maxBasisPoints caps a percentage tolerance at 10000% of the demand.
+const maxBasisPoints = 1_000_000
+
+// ParseTolerance reads either absolute units such as "15" or a percentage of the demand such as "2.5%".
+// An empty string accepts any overfill.
+func ParseTolerance(s string) (Tolerance, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Tolerance{}, nil
+	}
+
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		whole, frac, _ := strings.Cut(strings.TrimSpace(pct), ".")
+		if len(frac) > 2 {
+			return Tolerance{}, fmt.Errorf("tolerance %q: at most two decimal places", s)
+		}
+		bp, err := strconv.ParseInt(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+		if err != nil || whole == "" || bp < 0 || strings.HasPrefix(whole, "-") {
+			return Tolerance{}, fmt.Errorf("tolerance %q: must be a non-negative percentage", s)
+		}
+		if bp > maxBasisPoints {
+			return Tolerance{}, fmt.Errorf("tolerance %q: at most %d%%", s, maxBasisPoints/100)
+		}
+		return Tolerance{Limited: true, BasisPoints: bp}, nil
+	}
+
+	units, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || units < 0 {
+		return Tolerance{}, fmt.Errorf("tolerance %q: must be a non-negative number of units", s)
+	}
+	return Tolerance{Limited: true, Units: units}, nil
+}
+
+// Allowed returns the most overfill the tolerance accepts for demand, or -1 when it accepts any.
+func (t Tolerance) Allowed(demand int64) int64 {
+	if !t.Limited {
+		return -1
+	}
+	// Note: split demand so that only the whole part of the product can overflow, and saturate it.
+	demand = max(demand, 0)
+	pct := addSat(mulSat(demand/10_000, t.BasisPoints), demand%10_000*t.BasisPoints/10_000)
+	return max(t.Units, pct)
+}
+
+// Accepts reports whether an overfill is within the tolerance for demand.
+func (t Tolerance) Accepts(demand, overfill int64) bool {
+	allowed := t.Allowed(demand)
+	return allowed < 0 || overfill <= allowed
+}
+
+func (t Tolerance) String() string {
+	switch {
+	case !t.Limited:
+		return ""
+	case t.BasisPoints > 0:
+		return strings.TrimSuffix(strings.TrimSuffix(fmt.Sprintf("%d.%02d", t.BasisPoints/100, t.BasisPoints%100), "0"), ".0") + "%"
+	default:
+		return strconv.FormatInt(t.Units, 10)
+	}
+}
+
+func (t *Tolerance) UnmarshalText(text []byte) error {
+	parsed, err := ParseTolerance(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts a number of units as well as a string understood by ParseTolerance.
+func (t *Tolerance) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Tolerance{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return t.UnmarshalText([]byte(s))
+	}
+	return t.UnmarshalText(data)
+}
@@ -0,0 +1,89 @@
+package algorithms
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseTolerance(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Tolerance
+		wantErr bool
+	}{
+		{input: "", want: Tolerance{}},
+		{input: "0", want: Tolerance{Limited: true}},
+		{input: "15", want: Tolerance{Limited: true, Units: 15}},
+		{input: "5%", want: Tolerance{Limited: true, BasisPoints: 500}},
+		{input: "2.5%", want: Tolerance{Limited: true, BasisPoints: 250}},
+		{input: "0.05%", want: Tolerance{Limited: true, BasisPoints: 5}},
+		{input: "10000%", want: Tolerance{Limited: true, BasisPoints: 1_000_000}},
+		{input: "10000.01%", wantErr: true},
+		{input: "92233720368547758%", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "-1%", wantErr: true},
+		{input: "%", wantErr: true},
+		{input: "1.125%", wantErr: true},
+		{input: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTolerance(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTolerance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTolerance() = %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.input && tt.input != "0" {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestTolerance_Allowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance Tolerance
+		demand    int64
+		want      int64
+	}{
+		{name: "any", tolerance: Tolerance{}, demand: 100, want: -1},
+		{name: "exact", tolerance: Tolerance{Limited: true}, demand: 100, want: 0},
+		{name: "units", tolerance: Tolerance{Limited: true, Units: 7}, demand: 100, want: 7},
+		{name: "percentage rounds down", tolerance: Tolerance{Limited: true, BasisPoints: 250}, demand: 99, want: 2},
+		{name: "percentage of a huge demand", tolerance: Tolerance{Limited: true, BasisPoints: 5000}, demand: 1 << 62, want: 1 << 61},
+		{name: "percentage over the demand saturates", tolerance: Tolerance{Limited: true, BasisPoints: 1_000_000}, demand: 1 << 62, want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tolerance.Allowed(tt.demand); got != tt.want {
+				t.Errorf("Allowed() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTolerance_UnmarshalJSON(t *testing.T) {
+	var got struct {
+		Units   Tolerance `json:"units"`
+		Percent Tolerance `json:"percent"`
+		Missing Tolerance `json:"missing"`
+	}
+	if err := json.Unmarshal([]byte(`{"units": 0, "percent": "1.5%"}`), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if want := (Tolerance{Limited: true}); got.Units != want {
+		t.Errorf("units = %+v, want %+v", got.Units, want)
+	}
+	if want := (Tolerance{Limited: true, BasisPoints: 150}); got.Percent != want {
+		t.Errorf("percent = %+v, want %+v", got.Percent, want)
+	}
+	if got.Missing.Limited {
+		t.Errorf("missing = %+v, want any overfill", got.Missing)
+	}
+}
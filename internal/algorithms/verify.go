@@ -0,0 +1,292 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// ErrInvalidAllocation is returned when an allocator produced an allocation that breaks the rules of its sizes.
+var ErrInvalidAllocation = errors.New("invalid allocation")
+
+// Violation is one rule an allocation breaks, Size is empty for rules about the allocation as a whole.
+type Violation struct {
+	Size    pack.ID `json:"size,omitempty"`
+	Problem string  `json:"problem"`
+}
+
+func (v Violation) String() string {
+	if v.Size == "" {
+		return v.Problem
+	}
+	return fmt.Sprintf("size %s: %s", v.Size, v.Problem)
+}
+
+// VerificationError lists the violations found in an allocation.
+type VerificationError struct {
+	Violations []Violation
+}
+
+func (e *VerificationError) Error() string {
+	out := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		out[i] = v.String()
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidAllocation, strings.Join(out, "; "))
+}
+
+func (e *VerificationError) Unwrap() error {
+	return ErrInvalidAllocation
+}
+
+// Verify checks the rules every allocation of demand must follow whatever the options: known size IDs,
+// non-negative quantities respecting the constraints of their size and enough items to cover the demand.
+func Verify(sizes pack.Sizes, demand int64, result map[pack.ID]pack.Quantity) []Violation {
+	var out []Violation
+
+	ids := make([]pack.ID, 0, len(result))
+	for id := range result {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	items := int64(0)
+	for _, id := range ids {
+		q := result[id]
+		s, ok := sizes.ByID(id)
+		switch {
+		case !ok:
+			out = append(out, Violation{Size: id, Problem: "unknown size"})
+			continue
+		case q < 0:
+			out = append(out, Violation{Size: id, Problem: fmt.Sprintf("negative quantity %d", q)})
+			continue
+		}
+		items = addSat(items, mulSat(s.Capacity, int64(q)))
+	}
+
+	for _, s := range sizes {
+		q := result[s.ID]
+		if q < 0 || s.Allows(q) {
+			continue
+		}
+		switch {
+		case q == 0:
+			out = append(out, Violation{Size: s.ID, Problem: "required but not used"})
+		case q < s.MinQuantity:
+			out = append(out, Violation{Size: s.ID, Problem: fmt.Sprintf("quantity %d below the minimum of %d", q, s.MinQuantity)})
+		default:
+			out = append(out, Violation{Size: s.ID, Problem: fmt.Sprintf("quantity %d not a multiple of %d", q, s.Multiple)})
+		}
+	}
+
+	if items < demand {
+		out = append(out, Violation{Problem: fmt.Sprintf("%d items do not cover the demand of %d", items, demand)})
+	}
+
+	return out
+}
+
+// VerifyOptions extends Verify with the rules set by the options: stock when bounded and the overfill tolerance.
+func VerifyOptions(sizes pack.Sizes, demand int64, result map[pack.ID]pack.Quantity, opts Options) []Violation {
+	out := Verify(sizes, demand, result)
+	if len(out) > 0 {
+		return out
+	}
+
+	if opts.Bounded {
+		for _, s := range sizes {
+			if q := result[s.ID]; q > s.Stock {
+				out = append(out, Violation{Size: s.ID, Problem: fmt.Sprintf("quantity %d above the stock of %d", q, s.Stock)})
+			}
+		}
+	}
+
+	if overfill := measure(sizes, result)[1] - demand; !opts.MaxOverfill.Accepts(demand, overfill) {
+		out = append(out, Violation{Problem: fmt.Sprintf("overfill of %d above the allowed %d", overfill, opts.MaxOverfill.Allowed(demand))})
+	}
+
+	return out
+}
+
+// VerifyOptimal extends VerifyOptions by comparing the allocation with the best one found by trying every
+// combination of pack counts. Each combination counts as a cell of the budget of the options, so it only
+// suits small demands. Allocations are compared by Rank.
+func VerifyOptimal(ctx context.Context, sizes pack.Sizes, demand int64, result map[pack.ID]pack.Quantity, opts Options) ([]Violation, error) {
+	out := VerifyOptions(sizes, demand, result, opts)
+	if len(out) > 0 {
+		return out, nil
+	}
+
+	b := brute{
+		m:      MeterFor(ctx, opts),
+		sizes:  sizes,
+		demand: demand,
+		opts:   opts,
+		counts: make([]int64, len(sizes)),
+	}
+	if err := b.visit(0, 0); err != nil {
+		return nil, fmt.Errorf("searching for the optimal allocation: %w", err)
+	}
+	if b.best == nil {
+		return out, nil
+	}
+
+	got := RankOf(sizes, result, demand, opts)
+	if b.bestRank.Less(got) {
+		want := make(map[pack.ID]pack.Quantity)
+		for i, k := range b.best {
+			if k > 0 {
+				want[sizes[i].ID] = pack.Quantity(k)
+			}
+		}
+		out = append(out, Violation{Problem: fmt.Sprintf("not optimal, %s ranks better", describe(sizes, want))})
+	}
+
+	return out, nil
+}
+
+// Rank orders allocations the way the allocators settle ties: by cost for MinCost, then overfill, packs and,
+// for pack.PreferFewerSizes, distinct sizes. Smaller is better.
+type Rank [4]int64
+
+func (r Rank) Less(o Rank) bool {
+	for i := range r {
+		if r[i] != o[i] {
+			return r[i] < o[i]
+		}
+	}
+	return false
+}
+
+// RankOf ranks an allocation of demand under the options.
+func RankOf(sizes pack.Sizes, result map[pack.ID]pack.Quantity, demand int64, opts Options) Rank {
+	m := measure(sizes, result)
+	r := Rank{0, m[1] - demand, m[2], 0}
+	if opts.Objective == MinCost {
+		r[0] = m[0]
+	}
+	if opts.TieBreak.Rule == pack.PreferFewerSizes {
+		r[3] = m[3]
+	}
+	return r
+}
+
+// measure returns the cost, items, packs and distinct sizes of an allocation.
+func measure(sizes pack.Sizes, result map[pack.ID]pack.Quantity) [4]int64 {
+	var out [4]int64
+	for id, q := range result {
+		s, _ := sizes.ByID(id)
+		if q <= 0 {
+			continue
+		}
+		out[0] = addSat(out[0], mulSat(s.Price, int64(q)))
+		out[1] = addSat(out[1], mulSat(s.Capacity, int64(q)))
+		out[2] = addSat(out[2], int64(q))
+		out[3]++
+	}
+	return out
+}
+
+func describe(sizes pack.Sizes, result map[pack.ID]pack.Quantity) string {
+	var parts []string
+	for _, s := range sizes {
+		if q := result[s.ID]; q > 0 {
+			parts = append(parts, fmt.Sprintf("%d×%s", q, s.ID))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// brute tries every combination of pack counts that could be optimal.
+type brute struct {
+	m      *Meter
+	sizes  pack.Sizes
+	demand int64
+	opts   Options
+
+	counts   []int64
+	best     []int64
+	bestRank Rank
+}
+
+func (b *brute) visit(i int, units int64) error {
+	// Note: every combination tried counts as a cell, the search keeps nothing but it is what bounds its time.
+	if err := b.m.Reserve(1); err != nil {
+		return err
+	}
+	if err := b.m.Tick(); err != nil {
+		return err
+	}
+
+	if i == len(b.sizes) {
+		if units < b.demand {
+			return nil
+		}
+		result := make(map[pack.ID]pack.Quantity)
+		for j, k := range b.counts {
+			result[b.sizes[j].ID] = pack.Quantity(k)
+		}
+		if !b.opts.MaxOverfill.Accepts(b.demand, units-b.demand) {
+			return nil
+		}
+		r := RankOf(b.sizes, result, b.demand, b.opts)
+		if b.best == nil || r.Less(b.bestRank) {
+			b.best = append(b.best[:0], b.counts...)
+			b.bestRank = r
+		}
+		return nil
+	}
+
+	// Note: once the demand is covered more packs only add overfill, packs and cost, so every size is
+	// counted up to the first count covering what is left or its minimum, whichever is larger.
+	s := b.sizes[i]
+	top := max(ceilDiv(max(b.demand-units, 0), s.Capacity), int64(s.MinQuantity))
+	if s.Multiple > 1 {
+		top = ceilDiv(top, int64(s.Multiple)) * int64(s.Multiple)
+	}
+	if b.opts.Bounded {
+		top = min(top, int64(s.Stock))
+	}
+
+	for k := int64(0); k <= top; k++ {
+		if !s.Allows(pack.Quantity(k)) {
+			continue
+		}
+		b.counts[i] = k
+		err := b.visit(i+1, addSat(units, mulSat(k, s.Capacity)))
+		b.counts[i] = 0
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ceilDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 {
+		q++
+	}
+	return q
+}
+
+func mulSat(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+func addSat(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
@@ -0,0 +1,140 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestVerify(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 5},
+		{ID: "M", Capacity: 10, MinQuantity: 2, Multiple: 2},
+		{ID: "L", Capacity: 20, Required: true},
+	}
+
+	tests := []struct {
+		name   string
+		demand int64
+		result map[pack.ID]pack.Quantity
+		want   []Violation
+	}{
+		{
+			name:   "valid",
+			demand: 40,
+			result: map[pack.ID]pack.Quantity{"M": 2, "L": 1},
+		},
+		{
+			name:   "unknown size",
+			demand: 20,
+			result: map[pack.ID]pack.Quantity{"XL": 1, "L": 1},
+			want:   []Violation{{Size: "XL", Problem: "unknown size"}},
+		},
+		{
+			name:   "negative quantity",
+			demand: 20,
+			result: map[pack.ID]pack.Quantity{"S": -1, "L": 2},
+			want:   []Violation{{Size: "S", Problem: "negative quantity -1"}},
+		},
+		{
+			name:   "below minimum",
+			demand: 20,
+			result: map[pack.ID]pack.Quantity{"M": 1, "L": 1},
+			want:   []Violation{{Size: "M", Problem: "quantity 1 below the minimum of 2"}},
+		},
+		{
+			name:   "not a multiple",
+			demand: 50,
+			result: map[pack.ID]pack.Quantity{"M": 3, "L": 1},
+			want:   []Violation{{Size: "M", Problem: "quantity 3 not a multiple of 2"}},
+		},
+		{
+			name:   "required size missing and demand not covered",
+			demand: 20,
+			result: map[pack.ID]pack.Quantity{"S": 3},
+			want: []Violation{
+				{Size: "L", Problem: "required but not used"},
+				{Problem: "15 items do not cover the demand of 20"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Verify(sizes, tt.demand, tt.result)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyOptions(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "S", Capacity: 5, Stock: 2},
+		{ID: "L", Capacity: 20, Stock: 10},
+	}
+	result := map[pack.ID]pack.Quantity{"S": 3, "L": 1}
+
+	if got := VerifyOptions(sizes, 30, result, Options{}); len(got) != 0 {
+		t.Errorf("VerifyOptions() unbounded = %v, want none", got)
+	}
+
+	got := VerifyOptions(sizes, 30, result, Options{Bounded: true, MaxOverfill: Tolerance{Limited: true, Units: 2}})
+	want := []Violation{
+		{Size: "S", Problem: "quantity 3 above the stock of 2"},
+		{Problem: "overfill of 5 above the allowed 2"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("VerifyOptions() = %v, want %v", got, want)
+	}
+
+	err := error(&VerificationError{Violations: got})
+	if !errors.Is(err, ErrInvalidAllocation) {
+		t.Errorf("VerificationError does not wrap %v", ErrInvalidAllocation)
+	}
+}
+
+func TestVerifyOptimal(t *testing.T) {
+	sizes := pack.Sizes{
+		{ID: "A", Capacity: 23, Price: 250},
+		{ID: "B", Capacity: 31, Price: 320},
+		{ID: "C", Capacity: 53, Price: 490},
+	}
+
+	tests := []struct {
+		name    string
+		demand  int64
+		result  map[pack.ID]pack.Quantity
+		opts    Options
+		optimal bool
+	}{
+		{name: "exact fill", demand: 54, result: map[pack.ID]pack.Quantity{"A": 1, "B": 1}, optimal: true},
+		{name: "overfilled", demand: 54, result: map[pack.ID]pack.Quantity{"C": 2}},
+		{name: "more packs", demand: 106, result: map[pack.ID]pack.Quantity{"A": 2, "B": 2, "C": 0}},
+		{name: "fewest packs", demand: 106, result: map[pack.ID]pack.Quantity{"C": 2}, optimal: true},
+		{name: "cheaper", demand: 50, result: map[pack.ID]pack.Quantity{"A": 1, "B": 1}, opts: Options{Objective: MinCost}},
+		{name: "cheapest", demand: 50, result: map[pack.ID]pack.Quantity{"C": 1}, opts: Options{Objective: MinCost}, optimal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyOptimal(context.Background(), sizes, tt.demand, tt.result, tt.opts)
+			if err != nil {
+				t.Fatalf("VerifyOptimal() error = %v", err)
+			}
+			if (len(got) == 0) != tt.optimal {
+				t.Errorf("VerifyOptimal() = %v, want optimal %v", got, tt.optimal)
+			}
+		})
+	}
+
+	_, err := VerifyOptimal(context.Background(), sizesOf([]int64{1, 2, 3, 4, 5}), 5000, map[pack.ID]pack.Quantity{"5": 1000}, Options{Budget: Budget{MaxCells: 1000}})
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Errorf("VerifyOptimal() over budget error = %v, want %T", err, budgetErr)
+	}
+}
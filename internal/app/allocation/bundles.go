@@ -0,0 +1,181 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// BundleUse is a bundle taken by an allocation and how many of it.
+type BundleUse struct {
+	ID        pack.ID             `json:"id"`
+	Label     string              `json:"label"`
+	Contents  pack.BundleContents `json:"contents"`
+	Quantity  pack.Quantity       `json:"quantity"`
+	Price     int64               `json:"price"`
+	TotalCost int64               `json:"total_cost"`
+}
+
+// BundledLine is what one line of an order gets. Items and Overfill count the bundles as well, while Packs,
+// TotalCost and Allocations are of the single-SKU packs only.
+type BundledLine struct {
+	SKU string `json:"sku"`
+	Result
+	// FromBundles is the part of Items the bundles hold.
+	FromBundles pack.Decimal `json:"from_bundles"`
+}
+
+// Bundled is an order allocated with bundles and single-SKU packs, its lines matched to the order by index.
+type Bundled struct {
+	Bundles []BundleUse   `json:"bundles"`
+	Lines   []BundledLine `json:"lines"`
+	// Packs and TotalCost add up the bundles and the single-SKU packs of every line.
+	Packs     int64 `json:"packs"`
+	TotalCost int64 `json:"total_cost"`
+	Optimal   bool  `json:"optimal"`
+}
+
+// ComputeBundled allocates every line of an order together, using the single-SKU sizes of its inventory as
+// well as the bundles holding nothing but SKUs of the order. Every line keeps to the options on its own. The
+// problems of all lines are reported at once. Bundles are not stocked, they are put together when shipped.
+func (s *Service) ComputeBundled(ctx context.Context, order pack.Order, opts Options) (Bundled, error) {
+	opts = s.metered(ctx, opts)
+	bundles, err := s.repo.ListBundles(ctx)
+	if err != nil {
+		return Bundled{}, fmt.Errorf("listing bundles: %w", err)
+	}
+
+	type line struct {
+		inv  *pack.Inventory
+		name string
+	}
+	var (
+		errs  []error
+		lines = make([]line, len(order.Lines))
+		dims  = make([]algorithms.Dimension, len(order.Lines))
+		index = make(map[string]int, len(order.Lines))
+	)
+	for i, l := range order.Lines {
+		index[l.SKU] = i
+		inv, err := s.repo.GetInventory(ctx, l.SKU)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d (%s): %w", i+1, l.SKU, err))
+			continue
+		}
+		quantity, err := scale(inv, l.Quantity)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d (%s): %w", i+1, l.SKU, err))
+			continue
+		}
+		name, allocator, err := s.allocator(inv, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d (%s): %w", i+1, l.SKU, err))
+			continue
+		}
+		sizes, err := pick(inv, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d (%s): %w", i+1, l.SKU, err))
+			continue
+		}
+
+		lines[i] = line{inv: inv, name: name}
+		dims[i] = algorithms.Dimension{
+			Demand:    quantity,
+			Sizes:     sizes,
+			Allocator: allocator,
+			Options:   s.options(inv, opts),
+			Step:      inv.Measure().Step(),
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Bundled{}, err
+	}
+
+	var (
+		used  []pack.Bundle
+		sizes []algorithms.BundleSize
+	)
+	for _, b := range bundles {
+		size := algorithms.BundleSize{ID: b.ID, Price: b.Price, Contents: make([]int64, len(dims))}
+		fits := true
+		for _, item := range b.Contents {
+			i, ok := index[item.SKU]
+			if !ok {
+				fits = false
+				break
+			}
+			n, err := lines[i].inv.Measure().ScaleExact(item.Quantity)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("bundle %s: %s: %w", b.ID, item.SKU, err))
+				continue
+			}
+			size.Contents[i] = n
+		}
+		if fits {
+			used = append(used, b)
+			sizes = append(sizes, size)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Bundled{}, err
+	}
+
+	bundled, err := algorithms.AllocateBundled(ctx, dims, sizes, algorithms.Options{Objective: opts.Objective, Budget: s.budget, Meter: opts.Meter})
+	if err != nil {
+		return Bundled{}, fmt.Errorf("allocating bundles: %w", err)
+	}
+
+	out := Bundled{
+		Bundles: make([]BundleUse, 0, len(bundled.Bundles)),
+		Lines:   make([]BundledLine, len(order.Lines)),
+		Optimal: bundled.Proven,
+	}
+	covered := make([]int64, len(dims))
+	for k, b := range used {
+		q := bundled.Bundles[b.ID]
+		if q == 0 {
+			continue
+		}
+		out.Bundles = append(out.Bundles, BundleUse{
+			ID:        b.ID,
+			Label:     b.Label,
+			Contents:  b.Contents,
+			Quantity:  q,
+			Price:     b.Price,
+			TotalCost: pack.MulSat(int64(q), b.Price),
+		})
+		out.Packs = pack.AddSat(out.Packs, int64(q))
+		out.TotalCost = pack.AddSat(out.TotalCost, pack.MulSat(int64(q), b.Price))
+		for i, n := range sizes[k].Contents {
+			covered[i] += int64(q) * n
+		}
+	}
+
+	for i, l := range order.Lines {
+		d, m := dims[i], lines[i].inv.Measure()
+		dist := bundled.Singles[i]
+
+		// Note: the tolerance is of the whole line, the single-SKU packs only cover what the bundles leave.
+		verifyOpts := d.Options
+		verifyOpts.MaxOverfill = algorithms.Tolerance{}
+		if _, err := s.verify(ctx, d.Sizes, max(d.Demand-covered[i], 0), dist, verifyOpts, false); err != nil {
+			return Bundled{}, fmt.Errorf("line %d (%s): allocating with %s: %w", i+1, l.SKU, lines[i].name, err)
+		}
+
+		res := explain(m, d.Sizes, d.Demand, dist)
+		items := res.Allocations.SumItems() + covered[i]
+		res.Items = m.Decimal(items)
+		res.Overfill = m.Decimal(items - d.Demand)
+		res.Algorithm = lines[i].name
+		res.Optimal = bundled.Proven
+
+		out.Lines[i] = BundledLine{SKU: l.SKU, Result: res, FromBundles: m.Decimal(covered[i])}
+		out.Packs = pack.AddSat(out.Packs, res.Packs)
+		out.TotalCost = pack.AddSat(out.TotalCost, res.TotalCost)
+	}
+
+	return out, nil
+}
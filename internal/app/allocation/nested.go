@@ -0,0 +1,81 @@
+package allocation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// LevelResult is how the units of the level below are packed into the containers of one level.
+type LevelResult struct {
+	Name string `json:"name"`
+	// Units counts what the containers hold, Empty the slots left unused.
+	Units       int64            `json:"units"`
+	Containers  int64            `json:"containers"`
+	Empty       int64            `json:"empty"`
+	Allocations pack.Allocations `json:"allocations"`
+}
+
+// Nested is an allocation whose packs are packed into the packaging hierarchy of the inventory.
+type Nested struct {
+	Result
+	Levels []LevelResult `json:"levels"`
+	// Tree lists the outermost containers down to the packs.
+	Tree []pack.Node `json:"tree"`
+}
+
+// ComputeNested allocates the demand like Compute, then packs the packs into the containers of the first
+// level of the hierarchy, those into the containers of the second level and so on. Every level is allocated
+// with the allocator of the request for the fewest empty slots, then the fewest containers.
+func (s *Service) ComputeNested(ctx context.Context, sku string, demand pack.Amount, opts Options) (Nested, error) {
+	opts = s.metered(ctx, opts)
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return Nested{}, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	res, err := s.compute(ctx, inv, demand, opts)
+	if err != nil {
+		return Nested{}, err
+	}
+
+	name, allocator, err := s.allocator(inv, opts)
+	if err != nil {
+		return Nested{}, err
+	}
+
+	out := Nested{Result: res}
+	hierarchy := inv.Hierarchy()
+	containers := make([]pack.Allocations, len(hierarchy))
+	units := res.Packs
+	for i, level := range hierarchy {
+		levelOpts := algorithms.Options{Budget: s.budget, Meter: opts.Meter}
+		dist, err := allocator.Allocate(ctx, level.Sizes, units, levelOpts)
+		if err == nil {
+			_, err = s.verify(ctx, level.Sizes, units, dist, levelOpts, true)
+		}
+		if err != nil {
+			return Nested{}, fmt.Errorf("packing into %s with %s: %w", level.Name, name, err)
+		}
+
+		allocs := toAllocations(level.Sizes, dist)
+		out.Levels = append(out.Levels, LevelResult{
+			Name:        level.Name,
+			Units:       units,
+			Containers:  allocs.SumPacks(),
+			Empty:       allocs.SumItems() - units,
+			Allocations: allocs,
+		})
+		containers[i] = allocs
+		units = allocs.SumPacks()
+	}
+
+	out.Tree, err = hierarchy.Nest(inv.Measure(), res.Allocations, containers)
+	if err != nil {
+		return Nested{}, fmt.Errorf("nesting packs: %w", err)
+	}
+
+	return out, nil
+}
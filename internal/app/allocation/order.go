@@ -0,0 +1,79 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// LineResult is the allocation of one line of an order, Err is set instead when it failed.
+type LineResult struct {
+	Line   pack.OrderLine
+	Result Result
+	Err    error
+}
+
+// OrderTotals add up the lines of an order allocated so far. Items are left out as lines may be measured
+// in different units.
+type OrderTotals struct {
+	Lines     int   `json:"lines"`
+	Allocated int   `json:"allocated"`
+	Failed    int   `json:"failed"`
+	Packs     int64 `json:"packs"`
+	TotalCost int64 `json:"total_cost"`
+	// Weight is in grams, Volume in cubic centimetres.
+	Weight int64 `json:"weight"`
+	Volume int64 `json:"volume"`
+}
+
+// OrderResult holds the results of the lines of an order, matched to them by index.
+type OrderResult struct {
+	Lines  []LineResult
+	Totals OrderTotals
+}
+
+// Err joins the errors of the failed lines, it is nil when every line was allocated.
+func (o OrderResult) Err() error {
+	var errs []error
+	for _, line := range o.Lines {
+		if line.Err != nil {
+			errs = append(errs, line.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ComputeOrder allocates every line of an order like Compute, with the same options for each and one budget for
+// the whole order. A failing line does not stop the others, its error names the line and is kept in its result.
+// The returned error is only set when the order could not be finished, such as when the request is cancelled.
+func (s *Service) ComputeOrder(ctx context.Context, order pack.Order, opts Options) (OrderResult, error) {
+	opts = s.metered(ctx, opts)
+	out := OrderResult{
+		Lines:  make([]LineResult, len(order.Lines)),
+		Totals: OrderTotals{Lines: len(order.Lines)},
+	}
+	for i, line := range order.Lines {
+		res, err := s.Compute(ctx, line.SKU, line.Quantity, opts)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return OrderResult{}, fmt.Errorf("allocating order: %w", ctxErr)
+		}
+
+		out.Lines[i] = LineResult{Line: line}
+		if err != nil {
+			out.Lines[i].Err = fmt.Errorf("line %d (%s): %w", i+1, line.SKU, err)
+			out.Totals.Failed++
+			continue
+		}
+
+		out.Lines[i].Result = res
+		out.Totals.Allocated++
+		out.Totals.Packs = pack.AddSat(out.Totals.Packs, res.Packs)
+		out.Totals.TotalCost = pack.AddSat(out.Totals.TotalCost, res.TotalCost)
+		out.Totals.Weight = pack.AddSat(out.Totals.Weight, res.Allocations.TotalWeight())
+		out.Totals.Volume = pack.AddSat(out.Totals.Volume, res.Allocations.TotalVolume())
+	}
+
+	return out, nil
+}
@@ -0,0 +1,102 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Partial is what can be shipped from stock when it cannot cover the demand, along with the rest to backorder.
+// Its Result keeps the full demand while Items only counts what is shipped now.
+type Partial struct {
+	Result
+	// Shortfall is the part of the demand left uncovered by the stock, in the unit of the result.
+	Shortfall pack.Decimal `json:"shortfall"`
+	// Backorder suggests packs covering the shortfall once the stock is replenished, nil when nothing is short.
+	Backorder *Result `json:"backorder,omitempty"`
+}
+
+// ComputePartial allocates the demand like Compute. When the inventory tracks stock and it holds fewer items
+// than the demand, the allocation instead covers the most items the stock allows without going over the
+// demand, and the shortfall is allocated as a backorder ignoring stock. The options apply to the backorder,
+// the allocation from stock never overshoots.
+func (s *Service) ComputePartial(ctx context.Context, sku string, demand pack.Amount, opts Options) (Partial, error) {
+	opts = s.metered(ctx, opts)
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return Partial{}, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	res, err := s.compute(ctx, inv, demand, opts)
+	if err == nil {
+		return Partial{Result: res}, nil
+	}
+	// Note: only a stock holding fewer items than the demand is short, other failures have nothing to ship.
+	if !errors.Is(err, algorithms.ErrInsufficientStock) || !inv.TracksStock() {
+		return Partial{}, err
+	}
+
+	quantity, err := scale(inv, demand)
+	if err != nil {
+		return Partial{}, err
+	}
+
+	name, allocator, err := s.allocator(inv, opts)
+	if err != nil {
+		return Partial{}, err
+	}
+
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return Partial{}, err
+	}
+
+	m := inv.Measure()
+	algoOpts := s.options(inv, opts)
+	dist, err := algorithms.MostWithin(ctx, sizes, quantity, algoOpts)
+	if err != nil {
+		return Partial{}, fmt.Errorf("filling from stock: %w", err)
+	}
+
+	// Note: the allocator ranks the ways to ship the same items by the objective, when it finds one.
+	items := toAllocations(sizes, dist).SumItems()
+	exact := algoOpts
+	exact.MaxOverfill = algorithms.Tolerance{Limited: true}
+	optimal := false
+	if items > 0 {
+		better, ok, err := allocate(ctx, allocator, sizes, items, exact)
+		if cerr := ctx.Err(); cerr != nil {
+			return Partial{}, fmt.Errorf("allocating from stock with %s: %w", name, cerr)
+		}
+		if err == nil {
+			dist, optimal = better, ok
+		}
+	}
+	if _, err := s.verify(ctx, sizes, items, dist, exact, false); err != nil {
+		return Partial{}, fmt.Errorf("allocating from stock with %s: %w", name, err)
+	}
+
+	out := Partial{Result: explain(m, sizes, items, dist)}
+	out.Demand = m.Decimal(quantity)
+	out.Algorithm = name
+	out.Optimal = optimal
+
+	shortfall := quantity - items
+	out.Shortfall = m.Decimal(shortfall)
+
+	backOpts := algoOpts
+	backOpts.Bounded = false
+	back, ok, err := allocate(ctx, allocator, sizes, shortfall, backOpts)
+	if err != nil {
+		return Partial{}, fmt.Errorf("allocating backorder with %s: %w", name, inSteps(m, err))
+	}
+	backorder := explain(m, sizes, shortfall, back)
+	backorder.Algorithm = name
+	backorder.Optimal = ok
+	out.Backorder = &backorder
+
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package allocation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Change is the difference in packs of one size between the current allocation and the new one.
+type Change struct {
+	Size   pack.Size     `json:"size"`
+	Before pack.Quantity `json:"before"`
+	After  pack.Quantity `json:"after"`
+	Add    pack.Quantity `json:"add"`
+	Remove pack.Quantity `json:"remove"`
+}
+
+// Reallocation is the allocation of a changed demand along with the changes to pick.
+type Reallocation struct {
+	Demand      pack.Decimal     `json:"demand"`
+	Items       pack.Decimal     `json:"items"`
+	Overfill    pack.Decimal     `json:"overfill"`
+	Unit        pack.Unit        `json:"unit,omitempty"`
+	Packs       int64            `json:"packs"`
+	TotalCost   int64            `json:"total_cost"`
+	Allocations pack.Allocations `json:"allocations"`
+	// Changes lists every size of either allocation, Changed is how many packs they add and remove in total.
+	Changes []Change `json:"changes"`
+	Changed int64    `json:"changed"`
+}
+
+// Reallocate covers a changed demand changing the fewest packs of current, only the IDs of its sizes are
+// looked at. Sizes of current the options exclude are removed. The allocator of the request bounds the
+// search with an allocation from scratch.
+func (s *Service) Reallocate(ctx context.Context, sku string, current pack.Allocations, demand pack.Amount, opts Options) (Reallocation, error) {
+	opts = s.metered(ctx, opts)
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return Reallocation{}, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	quantity, err := scale(inv, demand)
+	if err != nil {
+		return Reallocation{}, err
+	}
+
+	name, allocator, err := s.allocator(inv, opts)
+	if err != nil {
+		return Reallocation{}, err
+	}
+
+	all := inv.AvailableSizes()
+	before := make(map[pack.ID]pack.Quantity)
+	for _, a := range current {
+		if _, ok := all.ByID(a.Size.ID); !ok {
+			return Reallocation{}, fmt.Errorf("%w: %s", pack.ErrUnknownSize, a.Size.ID)
+		}
+		before[a.Size.ID] += a.Quantity
+	}
+
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return Reallocation{}, err
+	}
+
+	kept := make(map[pack.ID]pack.Quantity)
+	for id, q := range before {
+		if _, ok := sizes.ByID(id); ok {
+			kept[id] = q
+		}
+	}
+
+	algoOpts := s.options(inv, opts)
+	after, err := algorithms.Reallocate(ctx, allocator, sizes, kept, quantity, algoOpts)
+	if err != nil {
+		return Reallocation{}, fmt.Errorf("reallocating with %s: %w", name, inSteps(inv.Measure(), err))
+	}
+	if _, err := s.verify(ctx, sizes, quantity, after, algoOpts, false); err != nil {
+		return Reallocation{}, fmt.Errorf("reallocating with %s: %w", name, inSteps(inv.Measure(), err))
+	}
+
+	res := explain(inv.Measure(), sizes, quantity, after)
+	out := Reallocation{
+		Demand:      res.Demand,
+		Items:       res.Items,
+		Overfill:    res.Overfill,
+		Unit:        res.Unit,
+		Packs:       res.Packs,
+		TotalCost:   res.TotalCost,
+		Allocations: res.Allocations,
+		Changed:     algorithms.Changes(before, after),
+	}
+	for _, size := range all {
+		b, a := before[size.ID], after[size.ID]
+		if b == 0 && a == 0 {
+			continue
+		}
+		out.Changes = append(out.Changes, Change{
+			Size:   size,
+			Before: b,
+			After:  a,
+			Add:    max(a-b, 0),
+			Remove: max(b-a, 0),
+		})
+	}
+
+	return out, nil
+}
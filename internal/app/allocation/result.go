@@ -0,0 +1,138 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Result is an allocation along with the measures explaining it.
+type Result struct {
+	// Algorithm names the allocator that produced the allocation.
+	Algorithm string `json:"algorithm"`
+	// Demand, Items and Overfill are in Unit, left out for pieces.
+	Demand    pack.Decimal `json:"demand"`
+	Items     pack.Decimal `json:"items"`
+	Overfill  pack.Decimal `json:"overfill"`
+	Unit      pack.Unit    `json:"unit,omitempty"`
+	Packs     int64        `json:"packs"`
+	TotalCost int64        `json:"total_cost"`
+	// Optimal is set when no allocation ranks better under the options: the allocator is exact, its
+	// quality bound is zero or strict mode compared it with brute force.
+	Optimal     bool             `json:"optimal"`
+	Allocations pack.Allocations `json:"allocations"`
+	// RunnerUp is the closest allocation that was passed over, nil when there is none.
+	RunnerUp *RunnerUp `json:"runner_up,omitempty"`
+	// RunnerUpError tells why no runner-up was looked for to the end, such as the budget running out.
+	RunnerUpError string `json:"runner_up_error,omitempty"`
+}
+
+// RunnerUp is the best of the allocations leaving out one of the sizes used, or covering more items.
+type RunnerUp struct {
+	Items       pack.Decimal     `json:"items"`
+	Overfill    pack.Decimal     `json:"overfill"`
+	Packs       int64            `json:"packs"`
+	TotalCost   int64            `json:"total_cost"`
+	Allocations pack.Allocations `json:"allocations"`
+	// Without names the size left out, it is empty when the runner-up covers more items instead.
+	Without pack.ID `json:"without,omitempty"`
+}
+
+func explain(m pack.Measure, sizes pack.Sizes, demand int64, dist map[pack.ID]pack.Quantity) Result {
+	allocs := toAllocations(sizes, dist)
+	return Result{
+		Demand:      m.Decimal(demand),
+		Items:       m.Decimal(allocs.SumItems()),
+		Overfill:    m.Decimal(allocs.SumItems() - demand),
+		Unit:        m.Unit,
+		Packs:       allocs.SumPacks(),
+		TotalCost:   allocs.TotalCost(),
+		Allocations: allocs,
+	}
+}
+
+// allocate runs the allocator and reports whether the allocation is known to be optimal.
+func allocate(ctx context.Context, a algorithms.Allocator, sizes pack.Sizes, demand int64, opts algorithms.Options) (map[pack.ID]pack.Quantity, bool, error) {
+	if exact, ok := a.(algorithms.ExactAllocator); ok {
+		dist, err := a.Allocate(ctx, sizes, demand, opts)
+		return dist, exact.Exact(), err
+	}
+	if est, ok := a.(algorithms.Estimator); ok {
+		e, err := est.Estimate(ctx, sizes, demand, opts)
+		return e.Packs, e.Gap == (algorithms.Gap{}), err
+	}
+
+	dist, err := a.Allocate(ctx, sizes, demand, opts)
+	return dist, false, err
+}
+
+// runnerUp asks the allocator for the allocation without each of the sizes dist uses, and for the one
+// covering at least one more item, then keeps the best ranked of them. Allocations the allocator cannot
+// find, or that break the options for demand, are not considered. Every allocation draws on the budget of
+// the options, running out of it or a cancelled request fails the search rather than keep what was found.
+func runnerUp(ctx context.Context, a algorithms.Allocator, m pack.Measure, sizes pack.Sizes, demand int64, dist map[pack.ID]pack.Quantity, opts algorithms.Options) (*RunnerUp, error) {
+	var (
+		best     *RunnerUp
+		bestRank algorithms.Rank
+	)
+	consider := func(cand map[pack.ID]pack.Quantity, without pack.ID) {
+		if len(algorithms.VerifyOptions(sizes, demand, cand, opts)) > 0 {
+			return
+		}
+		r := algorithms.RankOf(sizes, cand, demand, opts)
+		if best != nil && !r.Less(bestRank) {
+			return
+		}
+		res := explain(m, sizes, demand, cand)
+		best, bestRank = &RunnerUp{
+			Items:       res.Items,
+			Overfill:    res.Overfill,
+			Packs:       res.Packs,
+			TotalCost:   res.TotalCost,
+			Allocations: res.Allocations,
+			Without:     without,
+		}, r
+	}
+
+	for _, s := range sizes {
+		if dist[s.ID] <= 0 || s.Required {
+			continue
+		}
+		rest, err := sizes.Without([]pack.ID{s.ID})
+		if err != nil || len(rest) == 0 {
+			continue
+		}
+		cand, err := a.Allocate(ctx, rest, demand, opts)
+		if err := passedOver(err); err != nil {
+			return nil, err
+		}
+		if err == nil {
+			consider(cand, s.ID)
+		}
+	}
+
+	// Note: the tolerance is relative to the original demand, consider checks it.
+	more := opts
+	more.MaxOverfill = algorithms.Tolerance{}
+	items := toAllocations(sizes, dist).SumItems()
+	cand, err := a.Allocate(ctx, sizes, items+1, more)
+	if err := passedOver(err); err != nil {
+		return nil, err
+	}
+	if err == nil {
+		consider(cand, "")
+	}
+
+	return best, nil
+}
+
+// passedOver keeps the errors of a runner-up allocation that say nothing about the allocation itself.
+func passedOver(err error) error {
+	if errors.Is(err, algorithms.ErrBudgetExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("finding runner-up: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/algorithms/dp"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestRunnerUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   pack.Sizes
+		demand  int64
+		opts    algorithms.Options
+		dist    map[pack.ID]pack.Quantity
+		want    map[pack.ID]pack.Quantity
+		without pack.ID
+	}{
+		{
+			name:    "leaving out a size",
+			sizes:   pack.Sizes{{ID: "A", Capacity: 5}, {ID: "B", Capacity: 3}},
+			demand:  9,
+			dist:    map[pack.ID]pack.Quantity{"B": 3},
+			want:    map[pack.ID]pack.Quantity{"A": 2},
+			without: "B",
+		},
+		{
+			name:    "best of the sizes left out",
+			sizes:   pack.Sizes{{ID: "A", Capacity: 4}, {ID: "C", Capacity: 7}, {ID: "B", Capacity: 6}},
+			demand:  13,
+			dist:    map[pack.ID]pack.Quantity{"B": 1, "C": 1},
+			want:    map[pack.ID]pack.Quantity{"C": 2},
+			without: "B",
+		},
+		{
+			name:   "covering more items",
+			sizes:  pack.Sizes{{ID: "A", Capacity: 5}},
+			demand: 10,
+			dist:   map[pack.ID]pack.Quantity{"A": 2},
+			want:   map[pack.ID]pack.Quantity{"A": 3},
+		},
+		{
+			name:   "required size is kept",
+			sizes:  pack.Sizes{{ID: "A", Capacity: 5, Required: true}, {ID: "B", Capacity: 3}},
+			demand: 9,
+			dist:   map[pack.ID]pack.Quantity{"A": 2},
+			want:   map[pack.ID]pack.Quantity{"A": 1, "B": 2},
+		},
+		{
+			name:   "cost ranks before overfill",
+			sizes:  pack.Sizes{{ID: "A", Capacity: 5, Price: 1}, {ID: "B", Capacity: 3, Price: 10}},
+			demand: 9,
+			opts:   algorithms.Options{Objective: algorithms.MinCost},
+			dist:   map[pack.ID]pack.Quantity{"A": 2},
+			want:   map[pack.ID]pack.Quantity{"A": 3},
+		},
+		{
+			name:   "none within tolerance",
+			sizes:  pack.Sizes{{ID: "A", Capacity: 5}},
+			demand: 10,
+			opts:   algorithms.Options{MaxOverfill: algorithms.Tolerance{Limited: true}},
+			dist:   map[pack.ID]pack.Quantity{"A": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runnerUp(context.Background(), dp.Allocator{}, pack.Measure{}, tt.sizes, tt.demand, tt.dist, tt.opts)
+			if err != nil {
+				t.Fatalf("runnerUp() error = %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("runnerUp() = %+v, want none", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("runnerUp() = none, want %v", tt.want)
+			}
+
+			packs := make(map[pack.ID]pack.Quantity, len(got.Allocations))
+			for _, a := range got.Allocations {
+				packs[a.Size.ID] = a.Quantity
+			}
+			if !maps.Equal(packs, tt.want) || got.Without != tt.without {
+				t.Errorf("runnerUp() = %v without %q, want %v without %q", packs, got.Without, tt.want, tt.without)
+			}
+			if best, runner := algorithms.RankOf(tt.sizes, tt.dist, tt.demand, tt.opts), algorithms.RankOf(tt.sizes, packs, tt.demand, tt.opts); runner.Less(best) {
+				t.Errorf("runnerUp() ranks %v, better than the allocation at %v", runner, best)
+			}
+		})
+	}
+
+	t.Run("out of budget", func(t *testing.T) {
+		sizes := pack.Sizes{{ID: "A", Capacity: 5}, {ID: "B", Capacity: 3}}
+		opts := algorithms.Options{Budget: algorithms.Budget{MaxCells: 1}}
+
+		_, err := runnerUp(context.Background(), dp.Allocator{}, pack.Measure{}, sizes, 9, map[pack.ID]pack.Quantity{"B": 3}, opts)
+		if !errors.Is(err, algorithms.ErrBudgetExceeded) {
+			t.Errorf("runnerUp() error = %v, want %v", err, algorithms.ErrBudgetExceeded)
+		}
+	})
+}
@@ -2,49 +2,445 @@ package allocation
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/IAmRadek/packing/internal/algorithms"
 	"github.com/IAmRadek/packing/internal/domain/pack"
 )
 
+// ErrUnsatisfiable is returned when the sizes picked by a request rule out every allocation.
+var ErrUnsatisfiable = errors.New("request cannot be satisfied")
+
 type Repo interface {
 	GetInventory(ctx context.Context, sku string) (*pack.Inventory, error)
+	ListLocations(ctx context.Context, sku string) (pack.Locations, error)
+	ListBundles(ctx context.Context) ([]pack.Bundle, error)
 }
 
 type Service struct {
-	repo      Repo
-	allocator algorithms.Allocator
+	repo     Repo
+	registry *algorithms.Registry
+	budget   algorithms.Budget
+
+	fastName string
+	fast     algorithms.Estimator
+	accept   algorithms.Gap
+
+	shadow *shadow
+
+	strict      bool
+	optimalUpTo int64
+
+	shipping algorithms.ShipmentLimits
 }
 
-func NewService(repo Repo, algo algorithms.Allocator) *Service {
+// NewService allocates with the allocators of registry, inventories without an algorithm of their own
+// use the registry default.
+func NewService(repo Repo, registry *algorithms.Registry, budget algorithms.Budget) *Service {
 	return &Service{
-		repo:      repo,
-		allocator: algo,
+		repo:     repo,
+		registry: registry,
+		budget:   budget,
+	}
+}
+
+// SetFastPath makes Compute try the named allocator first and keep its answer when the gap is within accept.
+// Otherwise, or when it fails, Compute falls back to the default allocator. The fast path only applies when
+// neither the request nor the inventory picks an algorithm.
+func (s *Service) SetFastPath(name string, accept algorithms.Gap) error {
+	_, a, err := s.registry.Lookup(name)
+	if err != nil {
+		return err
+	}
+	est, ok := a.(algorithms.Estimator)
+	if !ok {
+		return fmt.Errorf("fast path with %s: %w", name, errors.ErrUnsupported)
+	}
+
+	s.fastName = name
+	s.fast = est
+	s.accept = accept
+	return nil
+}
+
+// SetStrict makes the service check every allocation with algorithms.Verify and reject the ones breaking
+// a rule with algorithms.ErrInvalidAllocation, whichever allocator produced them. Allocations of demands up
+// to optimalUpTo are also compared with brute force, unless they come from the fast path which is not
+// meant to be optimal. Comparisons running out of budget are skipped.
+func (s *Service) SetStrict(optimalUpTo int64) {
+	s.strict = true
+	s.optimalUpTo = optimalUpTo
+}
+
+// verify checks an allocation in strict mode, optimal asks for the brute force comparison when the demand
+// is small enough. It reports whether that comparison proved the allocation optimal.
+func (s *Service) verify(ctx context.Context, sizes pack.Sizes, demand int64, dist map[pack.ID]pack.Quantity, opts algorithms.Options, optimal bool) (bool, error) {
+	if !s.strict {
+		return false, nil
+	}
+
+	violations := algorithms.VerifyOptions(sizes, demand, dist, opts)
+	if len(violations) > 0 {
+		return false, &algorithms.VerificationError{Violations: violations}
+	}
+	if !optimal || demand > s.optimalUpTo {
+		return false, nil
+	}
+
+	violations, err := algorithms.VerifyOptimal(ctx, sizes, demand, dist, opts)
+	if errors.Is(err, algorithms.ErrBudgetExceeded) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(violations) > 0 {
+		return false, &algorithms.VerificationError{Violations: violations}
+	}
+	return true, nil
+}
+
+// Algorithms lists the names requests and inventories can pick an allocator by.
+func (s *Service) Algorithms() []string {
+	return s.registry.Names()
+}
+
+// DefaultAlgorithm is the allocator used when neither the request nor the inventory picks one.
+func (s *Service) DefaultAlgorithm() string {
+	return s.registry.Default()
+}
+
+// Options tune a single Compute call.
+type Options struct {
+	Objective algorithms.Objective
+	// Exclude lists sizes the allocation must not use.
+	Exclude []pack.ID
+	// Require lists sizes the allocation must use at least once.
+	Require []pack.ID
+	// MaxOverfill caps how far the allocation may overshoot the demand.
+	MaxOverfill algorithms.Tolerance
+	// Algorithm overrides the allocator picked by the inventory.
+	Algorithm string
+	// Meter is charged for every search of the call in place of a budget of its own, set it to bound several
+	// calls together. Calls sharing a meter must run one after another.
+	Meter *algorithms.Meter
+}
+
+// Meter starts a meter with the budget of the service, for Options shared by the calls of one request.
+func (s *Service) Meter(ctx context.Context) *algorithms.Meter {
+	return algorithms.NewMeter(ctx, s.budget)
+}
+
+// metered starts a meter for options without one, so every search of a call draws on the same budget.
+func (s *Service) metered(ctx context.Context, opts Options) Options {
+	if opts.Meter == nil {
+		opts.Meter = s.Meter(ctx)
 	}
+	return opts
 }
 
-func (s *Service) Compute(ctx context.Context, sku string, quantity int64) (pack.Allocations, error) {
+// Compute allocates a demand of an inventory, given in its measure or a unit convertible to it.
+func (s *Service) Compute(ctx context.Context, sku string, demand pack.Amount, opts Options) (Result, error) {
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return Result{}, fmt.Errorf("getting inventory: %w", err)
+	}
+	return s.compute(ctx, inv, demand, opts)
+}
+
+// compute is Compute for an inventory already at hand.
+func (s *Service) compute(ctx context.Context, inv *pack.Inventory, demand pack.Amount, opts Options) (Result, error) {
+	opts = s.metered(ctx, opts)
+	sku := inv.SKU()
+	quantity, err := scale(inv, demand)
+	if err != nil {
+		return Result{}, err
+	}
+
+	name, allocator, err := s.allocator(inv, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var (
+		dist    map[pack.ID]pack.Quantity
+		optimal bool
+		fast    bool
+	)
+	// Note: the fast path, the allocation, its check and its runner-up share the meter of the call.
+	algoOpts := s.options(inv, opts)
+
+	if s.fast != nil && opts.Algorithm == "" && inv.Algorithm() == "" {
+		est, err := s.fast.Estimate(ctx, sizes, quantity, algoOpts)
+		if err == nil && est.Gap.Within(s.accept) {
+			name, dist, optimal, fast = s.fastName, est.Packs, est.Gap == (algorithms.Gap{}), true
+		}
+	}
+
+	if dist == nil {
+		dist, optimal, err = allocate(ctx, allocator, sizes, quantity, algoOpts)
+	}
+	if err == nil {
+		var proved bool
+		proved, err = s.verify(ctx, sizes, quantity, dist, algoOpts, !fast)
+		optimal = optimal || proved
+	}
+
+	// Note: a cancelled request says nothing about the allocator, so it is not worth comparing.
+	if s.shadow != nil && ctx.Err() == nil {
+		// Note: the shadow runs alongside the request, it gets a budget of its own.
+		shadowOpts := algoOpts
+		shadowOpts.Meter = nil
+		m := inv.Measure()
+		s.shadow.run(ctx, sku, m, sizes, quantity, shadowOpts, name, outcome(m, toAllocations(sizes, dist), quantity, err))
+	}
+
+	if err != nil {
+		return Result{}, fmt.Errorf("allocating with %s: %w", name, inSteps(inv.Measure(), err))
+	}
+
+	res := explain(inv.Measure(), sizes, quantity, dist)
+	res.Algorithm = name
+	res.Optimal = optimal
+	// Note: the fast path is there to skip the allocator, the runner-up would run it anyway.
+	if !fast {
+		res.RunnerUp, err = runnerUp(ctx, allocator, inv.Measure(), sizes, quantity, dist, algoOpts)
+		if err != nil {
+			res.RunnerUpError = err.Error()
+		}
+	}
+
+	return res, nil
+}
+
+// allocator resolves the allocator of a request, falling back to the one of the inventory and then the default.
+func (s *Service) allocator(inv *pack.Inventory, opts Options) (string, algorithms.Allocator, error) {
+	name := opts.Algorithm
+	if name == "" {
+		name = inv.Algorithm()
+	}
+
+	name, a, err := s.registry.Lookup(name)
+	if err != nil {
+		return "", nil, fmt.Errorf("choosing algorithm: %w", err)
+	}
+	return name, a, nil
+}
+
+// BatchResult is the allocation of one quantity of a batch, Err is set instead when it failed.
+type BatchResult struct {
+	Allocations pack.Allocations
+	Algorithm   string
+	Err         error
+}
+
+// ComputeBatch allocates many demands of the same SKU, results are matched to demands by index.
+// Allocators implementing algorithms.BatchAllocator share their tables between the demands. The whole batch
+// draws on one budget.
+func (s *Service) ComputeBatch(ctx context.Context, sku string, demands []pack.Amount, opts Options) ([]BatchResult, error) {
+	opts = s.metered(ctx, opts)
 	inv, err := s.repo.GetInventory(ctx, sku)
 	if err != nil {
 		return nil, fmt.Errorf("getting inventory: %w", err)
 	}
 
-	sizes := inv.AvailableSizes()
+	quantities := make([]int64, len(demands))
+	for i, demand := range demands {
+		if quantities[i], err = scale(inv, demand); err != nil {
+			return nil, err
+		}
+	}
 
-	dist, err := s.allocator.Allocate(sizes, quantity)
+	name, allocator, err := s.allocator(inv, opts)
 	if err != nil {
-		return nil, fmt.Errorf("allocating: %w", err)
+		return nil, err
 	}
 
-	out := make(pack.Allocations, 0, len(dist))
-	for id, qty := range dist {
-		size, _ := sizes.ByID(id)
-		out = append(out, pack.Allocation{
-			Size:     size,
-			Quantity: qty,
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []algorithms.BatchResult
+	if batch, ok := allocator.(algorithms.BatchAllocator); ok {
+		results, err = batch.AllocateBatch(ctx, sizes, quantities, s.options(inv, opts))
+		if err != nil {
+			return nil, fmt.Errorf("allocating: %w", err)
+		}
+	} else {
+		results = make([]algorithms.BatchResult, len(quantities))
+		for i, quantity := range quantities {
+			results[i].Packs, results[i].Err = allocator.Allocate(ctx, sizes, quantity, s.options(inv, opts))
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("allocating: %w", err)
+			}
+		}
+	}
+
+	out := make([]BatchResult, len(results))
+	for i, res := range results {
+		if res.Err == nil {
+			_, res.Err = s.verify(ctx, sizes, quantities[i], res.Packs, s.options(inv, opts), true)
+		}
+		if res.Err != nil {
+			out[i].Err = fmt.Errorf("allocating with %s: %w", name, inSteps(inv.Measure(), res.Err))
+			continue
+		}
+		out[i].Allocations = toAllocations(sizes, res.Packs)
+		out[i].Algorithm = name
+	}
+
+	return out, nil
+}
+
+// Feasibility reports which quantities the sizes of an inventory can fill exactly.
+func (s *Service) Feasibility(ctx context.Context, sku string) (algorithms.Feasibility, error) {
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return algorithms.Feasibility{}, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	report, err := algorithms.AnalyzeFeasibility(ctx, inv.AvailableSizes(), s.budget)
+	if err != nil {
+		return algorithms.Feasibility{}, fmt.Errorf("analyzing feasibility: %w", err)
+	}
+
+	return report, nil
+}
+
+// Alternative is one of the non-dominated allocations for a demand.
+type Alternative struct {
+	Allocations pack.Allocations `json:"allocations"`
+	Overfill    pack.Decimal     `json:"overfill"`
+	Packs       int64            `json:"packs"`
+	Distinct    int              `json:"distinct_sizes"`
+}
+
+// Alternatives lists every allocation that is not worse than another one on overfill, packs and distinct sizes.
+func (s *Service) Alternatives(ctx context.Context, sku string, demand pack.Amount, opts Options) ([]Alternative, error) {
+	opts = s.metered(ctx, opts)
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return nil, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	quantity, err := scale(inv, demand)
+	if err != nil {
+		return nil, err
+	}
+
+	name, allocator, err := s.allocator(inv, opts)
+	if err != nil {
+		return nil, err
+	}
+	pareto, ok := allocator.(algorithms.ParetoAllocator)
+	if !ok {
+		return nil, fmt.Errorf("listing alternatives with %s: %w", name, errors.ErrUnsupported)
+	}
+
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	algoOpts := s.options(inv, opts)
+	alts, err := pareto.Pareto(ctx, sizes, quantity, algoOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing alternatives: %w", inSteps(inv.Measure(), err))
+	}
+
+	out := make([]Alternative, 0, len(alts))
+	for _, alt := range alts {
+		// Note: alternatives trade one measure for another, so only their validity is checked.
+		if _, err := s.verify(ctx, sizes, quantity, alt.Packs, algoOpts, false); err != nil {
+			return nil, fmt.Errorf("listing alternatives with %s: %w", name, inSteps(inv.Measure(), err))
+		}
+		out = append(out, Alternative{
+			Allocations: toAllocations(sizes, alt.Packs),
+			Overfill:    inv.Measure().Decimal(alt.Overfill),
+			Packs:       alt.PackCount,
+			Distinct:    alt.Distinct,
 		})
 	}
 
 	return out, nil
 }
+
+// pick narrows the sizes of the inventory down to the ones a request allows and marks the ones it requires.
+// They come in the order of its tie break, which the allocations are listed in.
+func pick(inv *pack.Inventory, opts Options) (pack.Sizes, error) {
+	for _, id := range opts.Require {
+		if slices.Contains(opts.Exclude, id) {
+			return nil, fmt.Errorf("%w: size %s is both required and excluded", ErrUnsatisfiable, id)
+		}
+	}
+
+	sizes, err := inv.TieBreak().Order(inv.AvailableSizes()).Without(opts.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("excluding sizes: %w", err)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("%w: every size is excluded", ErrUnsatisfiable)
+	}
+
+	sizes, err = sizes.Requiring(opts.Require)
+	if err != nil {
+		return nil, fmt.Errorf("requiring sizes: %w", err)
+	}
+
+	return sizes, nil
+}
+
+// scale converts a demand to whole steps of the measure of the inventory.
+func scale(inv *pack.Inventory, demand pack.Amount) (int64, error) {
+	quantity, err := inv.Measure().Scale(demand)
+	if err != nil {
+		return 0, fmt.Errorf("measuring demand: %w", err)
+	}
+	return quantity, nil
+}
+
+// inSteps notes the step of a fractional measure on an allocator error, the amounts it reports are in steps.
+func inSteps(m pack.Measure, err error) error {
+	if m.Precision == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (amounts in steps of %s)", err, m.Format(1))
+}
+
+func (s *Service) options(inv *pack.Inventory, opts Options) algorithms.Options {
+	// Note: tolerance units are whole units of the measure, the allocator works in steps of it. Units too many
+	// to count in steps saturate, no demand can overshoot by that much anyway.
+	tolerance := opts.MaxOverfill
+	tolerance.Units = pack.MulSat(tolerance.Units, inv.Measure().Step())
+
+	return algorithms.Options{
+		Bounded:     inv.TracksStock(),
+		Objective:   opts.Objective,
+		Budget:      s.budget,
+		Meter:       opts.Meter,
+		TieBreak:    inv.TieBreak(),
+		MaxOverfill: tolerance,
+	}
+}
+
+// toAllocations lists the packs of dist in the order of sizes.
+func toAllocations(sizes pack.Sizes, dist map[pack.ID]pack.Quantity) pack.Allocations {
+	out := make(pack.Allocations, 0, len(dist))
+	for _, size := range sizes {
+		if qty, ok := dist[size.ID]; ok {
+			out = append(out, pack.Allocation{
+				Size:     size,
+				Quantity: qty,
+			})
+		}
+	}
+	return out
+}
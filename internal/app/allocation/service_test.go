@@ -0,0 +1,56 @@
+package allocation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+func TestService_Options(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision int
+		tolerance algorithms.Tolerance
+		want      algorithms.Tolerance
+	}{
+		{
+			name:      "pieces",
+			tolerance: algorithms.Tolerance{Limited: true, Units: 15},
+			want:      algorithms.Tolerance{Limited: true, Units: 15},
+		},
+		{
+			name:      "units in steps",
+			precision: 3,
+			tolerance: algorithms.Tolerance{Limited: true, Units: 2, BasisPoints: 50},
+			want:      algorithms.Tolerance{Limited: true, Units: 2_000, BasisPoints: 50},
+		},
+		{
+			name:      "units too many to count in steps saturate",
+			precision: 3,
+			tolerance: algorithms.Tolerance{Limited: true, Units: math.MaxInt64 / 100},
+			want:      algorithms.Tolerance{Limited: true, Units: math.MaxInt64},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit := "kg"
+			if tt.precision == 0 {
+				unit = "pcs"
+			}
+			m, err := pack.NewMeasure(unit, tt.precision)
+			if err != nil {
+				t.Fatalf("NewMeasure() error = %v", err)
+			}
+			inv := pack.NewInventory("sku", pack.Sizes{{ID: "A", Capacity: 10}})
+			inv.SetMeasure(m)
+
+			got := NewService(nil, algorithms.NewRegistry(), algorithms.Budget{}).options(inv, Options{MaxOverfill: tt.tolerance})
+			if got.MaxOverfill != tt.want {
+				t.Errorf("options() tolerance = %+v, want %+v", got.MaxOverfill, tt.want)
+			}
+		})
+	}
+}
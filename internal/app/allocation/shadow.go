@@ -0,0 +1,215 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Outcome summarizes an allocation for comparison, Err is set instead when it failed.
+type Outcome struct {
+	Items    pack.Decimal `json:"items"`
+	Packs    int64        `json:"packs"`
+	Overfill pack.Decimal `json:"overfill"`
+	Err      string       `json:"error,omitempty"`
+}
+
+func outcome(m pack.Measure, allocs pack.Allocations, demand int64, err error) Outcome {
+	if err != nil {
+		return Outcome{Err: err.Error()}
+	}
+	items := allocs.SumItems()
+	return Outcome{
+		Items:    m.Decimal(items),
+		Packs:    allocs.SumPacks(),
+		Overfill: m.Decimal(items - demand),
+	}
+}
+
+// matches reports whether two outcomes agree, failures agree with each other whatever the error.
+func (o Outcome) matches(other Outcome) bool {
+	if (o.Err != "") != (other.Err != "") {
+		return false
+	}
+	return o.Err != "" || (o.Items == other.Items && o.Packs == other.Packs && o.Overfill == other.Overfill)
+}
+
+// Mismatch is a Compute call whose shadow allocation disagreed with the one served.
+type Mismatch struct {
+	At       time.Time    `json:"at"`
+	SKU      string       `json:"sku"`
+	Demand   pack.Decimal `json:"demand"`
+	Unit     pack.Unit    `json:"unit,omitempty"`
+	Primary  string       `json:"primary"`
+	Served   Outcome      `json:"served"`
+	Shadowed Outcome      `json:"shadowed"`
+}
+
+// ShadowStats counts shadow runs. Incomplete runs ran out of budget or time and were not compared, dropped
+// runs were skipped because too many were in flight.
+type ShadowStats struct {
+	Runs       int64 `json:"runs"`
+	Matches    int64 `json:"matches"`
+	Mismatches int64 `json:"mismatches"`
+	Incomplete int64 `json:"incomplete"`
+	Dropped    int64 `json:"dropped"`
+}
+
+// ShadowReport is the state of the shadow allocator, Recent lists the latest mismatches newest first.
+type ShadowReport struct {
+	Algorithm string      `json:"algorithm"`
+	Stats     ShadowStats `json:"stats"`
+	Recent    []Mismatch  `json:"recent"`
+}
+
+// shadow runs a secondary allocator next to the served one and records where they disagree.
+type shadow struct {
+	name      string
+	allocator algorithms.Allocator
+	log       *slog.Logger
+	slots     chan struct{}
+	keep      int
+
+	runs, matches, mismatches, incomplete, dropped atomic.Int64
+
+	mu     sync.Mutex
+	recent []Mismatch
+}
+
+// ShadowConfig tunes the shadow allocator.
+type ShadowConfig struct {
+	// Concurrency caps the shadow runs in flight, calls above it are dropped instead of queued.
+	Concurrency int
+	// Keep is how many recent mismatches are remembered.
+	Keep int
+}
+
+// SetShadow runs the named allocator after every Compute call without affecting its result, comparing
+// items, pack count and overfill with the allocation served. Mismatches are logged to log and kept for
+// ShadowReport.
+func (s *Service) SetShadow(name string, log *slog.Logger, cfg ShadowConfig) error {
+	name, a, err := s.registry.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	s.shadow = &shadow{
+		name:      name,
+		allocator: a,
+		log:       log,
+		slots:     make(chan struct{}, max(cfg.Concurrency, 1)),
+		keep:      max(cfg.Keep, 1),
+	}
+	return nil
+}
+
+// ShadowReport returns the counters and recent mismatches of the shadow allocator.
+// The report is empty when no shadow allocator is set.
+func (s *Service) ShadowReport() ShadowReport {
+	if s.shadow == nil {
+		return ShadowReport{}
+	}
+	return s.shadow.report()
+}
+
+// run compares the allocator against the served allocation in the background. The request context
+// only contributes its values, the comparison outlives the request.
+func (sh *shadow) run(ctx context.Context, sku string, m pack.Measure, sizes pack.Sizes, demand int64, opts algorithms.Options, primary string, served Outcome) {
+	if primary == sh.name {
+		return
+	}
+
+	select {
+	case sh.slots <- struct{}{}:
+	default:
+		sh.dropped.Add(1)
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() { <-sh.slots }()
+
+		dist, err := sh.allocator.Allocate(ctx, sizes, demand, opts)
+		sh.runs.Add(1)
+		// Note: a shadow stopped early says nothing about its allocator, only finished runs are compared.
+		if errors.Is(err, algorithms.ErrBudgetExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			sh.incomplete.Add(1)
+			return
+		}
+
+		shadowed := outcome(m, toAllocations(sizes, dist), demand, err)
+		if served.matches(shadowed) {
+			sh.matches.Add(1)
+			return
+		}
+		sh.mismatches.Add(1)
+
+		mismatch := Mismatch{
+			At:       time.Now(),
+			SKU:      sku,
+			Demand:   m.Decimal(demand),
+			Unit:     m.Unit,
+			Primary:  primary,
+			Served:   served,
+			Shadowed: shadowed,
+		}
+		sh.record(mismatch)
+
+		sh.log.LogAttrs(ctx, slog.LevelWarn, "shadow_allocation_mismatch",
+			slog.String("sku", sku),
+			slog.String("demand", m.Format(demand)),
+			slog.String("primary", primary),
+			slog.String("shadow", sh.name),
+			slog.Group("served",
+				slog.String("items", served.Items.String()),
+				slog.Int64("packs", served.Packs),
+				slog.String("overfill", served.Overfill.String()),
+				slog.String("error", served.Err),
+			),
+			slog.Group("shadowed",
+				slog.String("items", shadowed.Items.String()),
+				slog.Int64("packs", shadowed.Packs),
+				slog.String("overfill", shadowed.Overfill.String()),
+				slog.String("error", shadowed.Err),
+			),
+		)
+	}()
+}
+
+func (sh *shadow) record(m Mismatch) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.recent = append(sh.recent, m)
+	if len(sh.recent) > sh.keep {
+		sh.recent = sh.recent[len(sh.recent)-sh.keep:]
+	}
+}
+
+func (sh *shadow) report() ShadowReport {
+	sh.mu.Lock()
+	recent := make([]Mismatch, 0, len(sh.recent))
+	for i := len(sh.recent) - 1; i >= 0; i-- {
+		recent = append(recent, sh.recent[i])
+	}
+	sh.mu.Unlock()
+
+	return ShadowReport{
+		Algorithm: sh.name,
+		Stats: ShadowStats{
+			Runs:       sh.runs.Load(),
+			Matches:    sh.matches.Load(),
+			Mismatches: sh.mismatches.Load(),
+			Incomplete: sh.incomplete.Load(),
+			Dropped:    sh.dropped.Load(),
+		},
+		Recent: recent,
+	}
+}
@@ -0,0 +1,177 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// stubAllocator answers every demand with the same packs or error.
+type stubAllocator struct {
+	packs map[pack.ID]pack.Quantity
+	err   error
+}
+
+func (a stubAllocator) Allocate(context.Context, pack.Sizes, int64, algorithms.Options) (map[pack.ID]pack.Quantity, error) {
+	return a.packs, a.err
+}
+
+func TestOutcome_Matches(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Outcome
+		want bool
+	}{
+		{
+			name: "same allocation",
+			a:    Outcome{Items: pack.Decimal{Value: 30}, Packs: 2, Overfill: pack.Decimal{Value: 1}},
+			b:    Outcome{Items: pack.Decimal{Value: 30}, Packs: 2, Overfill: pack.Decimal{Value: 1}},
+			want: true,
+		},
+		{
+			name: "more packs",
+			a:    Outcome{Items: pack.Decimal{Value: 30}, Packs: 2, Overfill: pack.Decimal{Value: 1}},
+			b:    Outcome{Items: pack.Decimal{Value: 30}, Packs: 3, Overfill: pack.Decimal{Value: 1}},
+			want: false,
+		},
+		{
+			name: "more overfill",
+			a:    Outcome{Items: pack.Decimal{Value: 30}, Packs: 2, Overfill: pack.Decimal{Value: 1}},
+			b:    Outcome{Items: pack.Decimal{Value: 31}, Packs: 2, Overfill: pack.Decimal{Value: 2}},
+			want: false,
+		},
+		{
+			name: "both failed with different errors",
+			a:    Outcome{Err: "insufficient stock to cover demand"},
+			b:    Outcome{Err: "no allocation satisfies the pack constraints"},
+			want: true,
+		},
+		{
+			name: "only one failed",
+			a:    Outcome{Items: pack.Decimal{Value: 30}, Packs: 2},
+			b:    Outcome{Err: "insufficient stock to cover demand"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.matches(tt.b); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+			if got := tt.b.matches(tt.a); got != tt.want {
+				t.Errorf("matches() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShadow_Record(t *testing.T) {
+	sh := &shadow{keep: 2}
+	for _, sku := range []string{"a", "b", "c"} {
+		sh.record(Mismatch{SKU: sku})
+	}
+
+	got := sh.report().Recent
+	want := []Mismatch{{SKU: "c"}, {SKU: "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("report() recent = %v, want %v", got, want)
+	}
+}
+
+func TestShadow_Run(t *testing.T) {
+	sizes := pack.Sizes{{ID: "A", Capacity: 10}}
+	served := outcome(pack.Measure{}, toAllocations(sizes, map[pack.ID]pack.Quantity{"A": 3}), 25, nil)
+
+	tests := []struct {
+		name      string
+		allocator stubAllocator
+		want      ShadowStats
+		recent    int
+	}{
+		{
+			name:      "match",
+			allocator: stubAllocator{packs: map[pack.ID]pack.Quantity{"A": 3}},
+			want:      ShadowStats{Runs: 1, Matches: 1},
+		},
+		{
+			name:      "mismatch",
+			allocator: stubAllocator{packs: map[pack.ID]pack.Quantity{"A": 4}},
+			want:      ShadowStats{Runs: 1, Mismatches: 1},
+			recent:    1,
+		},
+		{
+			name:      "failure",
+			allocator: stubAllocator{err: algorithms.ErrInfeasible},
+			want:      ShadowStats{Runs: 1, Mismatches: 1},
+			recent:    1,
+		},
+		{
+			name:      "out of budget",
+			allocator: stubAllocator{err: &algorithms.BudgetError{Resource: algorithms.Cells}},
+			want:      ShadowStats{Runs: 1, Incomplete: 1},
+		},
+		{
+			name:      "out of time",
+			allocator: stubAllocator{err: context.DeadlineExceeded},
+			want:      ShadowStats{Runs: 1, Incomplete: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh := &shadow{
+				name:      "shadow",
+				allocator: tt.allocator,
+				log:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+				slots:     make(chan struct{}, 1),
+				keep:      10,
+			}
+
+			sh.run(context.Background(), "sku", pack.Measure{}, sizes, 25, algorithms.Options{}, "primary", served)
+			// Note: the slot frees up once the run is recorded.
+			sh.slots <- struct{}{}
+
+			got := sh.report()
+			if got.Stats != tt.want {
+				t.Errorf("report() stats = %+v, want %+v", got.Stats, tt.want)
+			}
+			if len(got.Recent) != tt.recent {
+				t.Errorf("report() recent = %v, want %d mismatches", got.Recent, tt.recent)
+			}
+		})
+	}
+
+	t.Run("dropped", func(t *testing.T) {
+		sh := &shadow{
+			name:      "shadow",
+			allocator: stubAllocator{err: errors.New("must not run")},
+			slots:     make(chan struct{}, 1),
+			keep:      10,
+		}
+		sh.slots <- struct{}{}
+
+		sh.run(context.Background(), "sku", pack.Measure{}, sizes, 25, algorithms.Options{}, "primary", served)
+		sh.run(context.Background(), "sku", pack.Measure{}, sizes, 25, algorithms.Options{}, "primary", served)
+
+		if got, want := sh.report().Stats, (ShadowStats{Dropped: 2}); got != want {
+			t.Errorf("report() stats = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("same allocator", func(t *testing.T) {
+		sh := &shadow{name: "shadow", slots: make(chan struct{}, 1), keep: 10}
+
+		sh.run(context.Background(), "sku", pack.Measure{}, sizes, 25, algorithms.Options{}, "shadow", served)
+
+		if got := sh.report().Stats; got != (ShadowStats{}) {
+			t.Errorf("report() stats = %+v, want none", got)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package allocation
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// Shipment is the packs travelling together along with their totals.
+type Shipment struct {
+	Allocations pack.Allocations `json:"allocations"`
+	Items       pack.Decimal     `json:"items"`
+	Packs       int64            `json:"packs"`
+	// Weight is in grams, Volume in cubic centimetres.
+	Weight int64 `json:"weight"`
+	Volume int64 `json:"volume"`
+}
+
+// Shipped is an allocation split into shipments within the limits of a carrier.
+type Shipped struct {
+	Result
+	Limits    algorithms.ShipmentLimits `json:"shipment_limits"`
+	Shipments []Shipment                `json:"shipments"`
+	// Fewest is set when no allocation tied with the one shipped on the objective and overfill splits into
+	// fewer shipments within the limits.
+	Fewest bool `json:"fewest_shipments"`
+}
+
+// SetShipmentLimits sets the limits ComputeShipments applies to requests that give none.
+func (s *Service) SetShipmentLimits(limits algorithms.ShipmentLimits) {
+	s.shipping = limits
+}
+
+// ShipmentLimits are the limits applied to requests that give none.
+func (s *Service) ShipmentLimits() algorithms.ShipmentLimits {
+	return s.shipping
+}
+
+// ComputeShipments allocates the demand like Compute, so the overfill stays the smallest the options allow,
+// leaving out the sizes over the limits unless the request requires them. Among the allocations tied with it
+// on the objective and overfill it picks the one splitting into the fewest shipments within limits. Limits
+// capping nothing fall back to the ones set with SetShipmentLimits. Packs without a weight or dimensions count
+// as weightless and taking no room.
+func (s *Service) ComputeShipments(ctx context.Context, sku string, demand pack.Amount, opts Options, limits algorithms.ShipmentLimits) (Shipped, error) {
+	opts = s.metered(ctx, opts)
+	if !limits.Limited() {
+		limits = s.shipping
+	}
+
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return Shipped{}, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	// Note: packs over the limits cannot ship at all, so allocations using them are never worth finding.
+	opts.Exclude = slices.Clone(opts.Exclude)
+	fitting := 0
+	for _, size := range inv.AvailableSizes() {
+		switch {
+		case limits.Fits(size):
+			fitting++
+		case !slices.Contains(opts.Require, size.ID) && !slices.Contains(opts.Exclude, size.ID):
+			opts.Exclude = append(opts.Exclude, size.ID)
+		}
+	}
+	if fitting == 0 {
+		return Shipped{}, fmt.Errorf("splitting into shipments: every size: %w", algorithms.ErrOverLimit)
+	}
+
+	res, err := s.compute(ctx, inv, demand, opts)
+	if err != nil {
+		return Shipped{}, err
+	}
+
+	quantity, err := scale(inv, demand)
+	if err != nil {
+		return Shipped{}, err
+	}
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return Shipped{}, err
+	}
+	dist := make(map[pack.ID]pack.Quantity, len(res.Allocations))
+	for _, a := range res.Allocations {
+		dist[a.Size.ID] += a.Quantity
+	}
+
+	best, split, fewest, err := algorithms.ShipFewest(ctx, sizes, dist, limits, s.options(inv, opts))
+	if err != nil {
+		return Shipped{}, fmt.Errorf("splitting into shipments: %w", err)
+	}
+	if !maps.Equal(best, dist) {
+		// Note: the runner-up was found for the allocation replaced, it is no longer the next best.
+		chosen := explain(inv.Measure(), sizes, quantity, best)
+		chosen.Algorithm = res.Algorithm
+		chosen.Optimal = res.Optimal && fewest
+		res = chosen
+	}
+
+	out := Shipped{
+		Result: res,
+		Limits: limits,
+		Fewest: fewest,
+	}
+	for _, packs := range split {
+		allocs := toAllocations(sizes, packs)
+		shipment := Shipment{
+			Allocations: allocs,
+			Packs:       allocs.SumPacks(),
+			Weight:      allocs.TotalWeight(),
+			Volume:      allocs.TotalVolume(),
+		}
+		if len(allocs) > 0 {
+			shipment.Items = allocs[0].Size.Measure.Decimal(allocs.SumItems())
+		}
+		out.Shipments = append(out.Shipments, shipment)
+	}
+
+	return out, nil
+}
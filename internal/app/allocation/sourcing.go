@@ -0,0 +1,108 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+)
+
+// ErrNoLocations is returned when sourcing from an inventory not stocked in any location.
+var ErrNoLocations = errors.New("inventory has no locations")
+
+// LocationResult is the part of an allocation taken from one location.
+type LocationResult struct {
+	Location     string           `json:"location"`
+	ShippingCost int64            `json:"shipping_cost"`
+	Items        pack.Decimal     `json:"items"`
+	Packs        int64            `json:"packs"`
+	TotalCost    int64            `json:"total_cost"`
+	Allocations  pack.Allocations `json:"allocations"`
+}
+
+// Sourced is an allocation taken from the stock of several locations, grouped by location.
+type Sourced struct {
+	Result
+	Locations []LocationResult `json:"locations"`
+	// ShippingCost adds up the locations used, Cost adds it to the price of the packs.
+	ShippingCost int64 `json:"shipping_cost"`
+	Cost         int64 `json:"cost"`
+}
+
+// ComputeSourced allocates the demand from the stock of the locations of the inventory for the lowest cost of
+// packs and shipping, then the fewest locations. The objective of the options is ignored, the rest apply to the
+// allocation as a whole. The stock of the sizes themselves is not used.
+func (s *Service) ComputeSourced(ctx context.Context, sku string, demand pack.Amount, opts Options) (Sourced, error) {
+	opts = s.metered(ctx, opts)
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return Sourced{}, fmt.Errorf("getting inventory: %w", err)
+	}
+
+	locations, err := s.repo.ListLocations(ctx, sku)
+	if err != nil {
+		return Sourced{}, fmt.Errorf("listing locations: %w", err)
+	}
+	if len(locations) == 0 {
+		return Sourced{}, fmt.Errorf("%w: %s", ErrNoLocations, sku)
+	}
+
+	quantity, err := scale(inv, demand)
+	if err != nil {
+		return Sourced{}, err
+	}
+
+	name, allocator, err := s.allocator(inv, opts)
+	if err != nil {
+		return Sourced{}, err
+	}
+
+	sizes, err := pick(inv, opts)
+	if err != nil {
+		return Sourced{}, err
+	}
+
+	algoOpts := s.options(inv, opts)
+	sourcing, err := algorithms.Source(ctx, allocator, sizes, locations, quantity, algoOpts)
+	if err != nil {
+		return Sourced{}, fmt.Errorf("sourcing with %s: %w", name, inSteps(inv.Measure(), err))
+	}
+
+	m := inv.Measure()
+	dist := make(map[pack.ID]pack.Quantity)
+	out := Sourced{Cost: sourcing.Cost}
+	for _, loc := range locations {
+		packs, ok := sourcing.Packs[loc.ID]
+		if !ok {
+			continue
+		}
+		allocs := toAllocations(sizes, packs)
+		out.Locations = append(out.Locations, LocationResult{
+			Location:     loc.ID,
+			ShippingCost: loc.ShippingCost,
+			Items:        m.Decimal(allocs.SumItems()),
+			Packs:        allocs.SumPacks(),
+			TotalCost:    allocs.TotalCost(),
+			Allocations:  allocs,
+		})
+		out.ShippingCost = pack.AddSat(out.ShippingCost, loc.ShippingCost)
+		for id, q := range packs {
+			dist[id] += q
+		}
+	}
+
+	// Note: the split keeps to the stock of every location, the stock of the sizes does not bound it.
+	verifyOpts := algoOpts
+	verifyOpts.Bounded = false
+	if _, err := s.verify(ctx, sizes, quantity, dist, verifyOpts, false); err != nil {
+		return Sourced{}, fmt.Errorf("sourcing with %s: %w", name, err)
+	}
+
+	out.Result = explain(m, sizes, quantity, dist)
+	out.Algorithm = name
+	out.Optimal = sourcing.Proven
+
+	return out, nil
+}
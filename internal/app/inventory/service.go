@@ -2,6 +2,7 @@ package inventory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -14,6 +15,11 @@ type Repo interface {
 	GetInventory(ctx context.Context, sku string) (*pack.Inventory, error)
 	DeleteInventory(ctx context.Context, sku string) error
 	Save(ctx context.Context, inv *pack.Inventory) error
+	ListLocations(ctx context.Context, sku string) (pack.Locations, error)
+	SaveLocations(ctx context.Context, sku string, locations pack.Locations) error
+	ListBundles(ctx context.Context) ([]pack.Bundle, error)
+	SaveBundle(ctx context.Context, b pack.Bundle) error
+	DeleteBundle(ctx context.Context, id pack.ID) error
 }
 
 type Service struct {
@@ -39,8 +45,29 @@ func (s *Service) List(ctx context.Context) ([]*pack.Inventory, error) {
 	return lst, nil
 }
 
-func (s *Service) Create(ctx context.Context, sku string, sizes []pack.Size) error {
+// Settings are the allocation preferences stored with an inventory.
+type Settings struct {
+	TrackStock bool
+	TieBreak   pack.TieBreak
+	// Algorithm names the allocator, empty for the server default.
+	Algorithm string
+	// Measure is the unit capacities and demands are given in, the capacities of the sizes are scaled to it.
+	Measure pack.Measure
+	// Hierarchy is the packaging packs are nested in, empty to ship bare packs.
+	Hierarchy pack.Hierarchy
+}
+
+func (s Settings) apply(inv *pack.Inventory) {
+	inv.TrackStock(s.TrackStock)
+	inv.SetTieBreak(s.TieBreak)
+	inv.SetAlgorithm(s.Algorithm)
+	inv.SetMeasure(s.Measure)
+	inv.SetHierarchy(s.Hierarchy)
+}
+
+func (s *Service) Create(ctx context.Context, sku string, sizes []pack.Size, settings Settings) error {
 	inv := pack.NewInventory(sku, sizes)
+	settings.apply(inv)
 	return s.repo.Save(ctx, inv)
 }
 
@@ -48,17 +75,66 @@ func (s *Service) Get(ctx context.Context, sku string) (*pack.Inventory, error)
 	return s.repo.GetInventory(ctx, sku)
 }
 
-func (s *Service) Update(ctx context.Context, sku string, sizes []pack.Size) error {
+func (s *Service) Update(ctx context.Context, sku string, sizes []pack.Size, settings Settings) error {
 	inv, err := s.repo.GetInventory(ctx, sku)
 	if err != nil {
 		return fmt.Errorf("getting inventory: %w", err)
 	}
 
 	inv.Update(sizes)
+	settings.apply(inv)
 
 	return s.repo.Save(ctx, inv)
 }
 
+// Locations lists the warehouses stocking the inventory of sku.
+func (s *Service) Locations(ctx context.Context, sku string) (pack.Locations, error) {
+	return s.repo.ListLocations(ctx, sku)
+}
+
+// SetLocations replaces the warehouses stocking the inventory of sku, their stock must be of its sizes.
+func (s *Service) SetLocations(ctx context.Context, sku string, locations pack.Locations) error {
+	inv, err := s.repo.GetInventory(ctx, sku)
+	if err != nil {
+		return fmt.Errorf("getting inventory: %w", err)
+	}
+
+	if err := locations.Validate(inv.AvailableSizes()); err != nil {
+		return err
+	}
+
+	return s.repo.SaveLocations(ctx, sku, locations)
+}
+
+// Bundles lists the bundles of every inventory.
+func (s *Service) Bundles(ctx context.Context) ([]pack.Bundle, error) {
+	return s.repo.ListBundles(ctx)
+}
+
+// SaveBundle adds or replaces a bundle. Every item must be of an existing inventory, in whole steps of its measure.
+func (s *Service) SaveBundle(ctx context.Context, b pack.Bundle) error {
+	var errs []error
+	for _, item := range b.Contents {
+		inv, err := s.repo.GetInventory(ctx, item.SKU)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := inv.Measure().ScaleExact(item.Quantity); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.SKU, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	return s.repo.SaveBundle(ctx, b)
+}
+
+func (s *Service) DeleteBundle(ctx context.Context, id pack.ID) error {
+	return s.repo.DeleteBundle(ctx, id)
+}
+
 func (s *Service) Delete(ctx context.Context, sku string) error {
 	return s.repo.DeleteInventory(ctx, sku)
 }
@@ -0,0 +1,90 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BundleItem is the quantity of one SKU a bundle holds, in the measure of its inventory unless it names a unit.
+type BundleItem struct {
+	SKU      string `json:"sku"`
+	Quantity Amount `json:"quantity"`
+}
+
+// BundleContents are the items of a bundle, each of a different SKU.
+type BundleContents []BundleItem
+
+// ParseBundleContents reads items separated by commas, each a SKU and an amount separated by a colon, such as
+// "tires: 4, rims: 2.5 kg".
+func ParseBundleContents(s string) (BundleContents, error) {
+	var out BundleContents
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		sku, amount, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("contents %q: must be sku: quantity", part)
+		}
+		quantity, err := ParseAmount(amount)
+		if err != nil {
+			return nil, fmt.Errorf("contents %q: %w", part, err)
+		}
+		out = append(out, BundleItem{SKU: strings.TrimSpace(sku), Quantity: quantity})
+	}
+	return out, nil
+}
+
+func (c BundleContents) String() string {
+	parts := make([]string, len(c))
+	for i, item := range c {
+		parts[i] = item.SKU + ": " + item.Quantity.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Bundle is a pack holding fixed quantities of several SKUs, such as a variety box of 4 of one and 2 of another.
+type Bundle struct {
+	ID    ID     `json:"id"`
+	Label string `json:"label"`
+	// Price is the cost of a single bundle in minor currency units.
+	Price    int64          `json:"price"`
+	Contents BundleContents `json:"contents"`
+}
+
+// NewBundle checks the bundle has an ID, a price that is not negative and at least one item, every item of
+// a SKU not repeated by another and a positive quantity, reporting all the problems at once.
+func NewBundle(id ID, label string, price int64, contents BundleContents) (Bundle, error) {
+	var errs []error
+	if id == "" {
+		errs = append(errs, errors.New("id is required"))
+	}
+	if price < 0 {
+		errs = append(errs, errors.New("price must not be negative"))
+	}
+	if len(contents) == 0 {
+		errs = append(errs, errors.New("bundle holds no items"))
+	}
+
+	seen := make(map[string]bool, len(contents))
+	for i, item := range contents {
+		switch {
+		case item.SKU == "":
+			errs = append(errs, fmt.Errorf("item %d: sku is required", i+1))
+		case seen[item.SKU]:
+			errs = append(errs, fmt.Errorf("item %d: sku %s is listed twice", i+1, item.SKU))
+		}
+		seen[item.SKU] = true
+
+		if item.Quantity.Value.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("item %d: quantity must be positive", i+1))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Bundle{}, err
+	}
+
+	return Bundle{ID: id, Label: label, Price: price, Contents: contents}, nil
+}
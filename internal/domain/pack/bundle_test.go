@@ -0,0 +1,84 @@
+package pack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBundleContents(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "tires: 4, rims: 2", want: "tires: 4, rims: 2"},
+		{in: " flour:2.5 kg ,, ", want: "flour: 2.5 kg"},
+		{in: "", want: ""},
+		{in: "tires 4", wantErr: true},
+		{in: "tires: four", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseBundleContents(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBundleContents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseBundleContents() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBundle(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       ID
+		price    int64
+		contents BundleContents
+		wantErr  []string
+	}{
+		{
+			name:     "valid",
+			id:       "variety",
+			price:    900,
+			contents: BundleContents{{SKU: "tires", Quantity: Pieces(4)}, {SKU: "rims", Quantity: Pieces(2)}},
+		},
+		{
+			name:    "empty",
+			price:   -1,
+			wantErr: []string{"id is required", "price must not be negative", "holds no items"},
+		},
+		{
+			name: "every item checked",
+			id:   "variety",
+			contents: BundleContents{
+				{SKU: "tires", Quantity: Pieces(4)},
+				{SKU: "", Quantity: Pieces(1)},
+				{SKU: "tires", Quantity: Pieces(0)},
+			},
+			wantErr: []string{"item 2: sku is required", "item 3: sku tires is listed twice", "item 3: quantity must be positive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBundle(tt.id, "", tt.price, tt.contents)
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("NewBundle() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("NewBundle() error = %v, want it to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if got.ID != tt.id || len(got.Contents) != len(tt.contents) {
+				t.Errorf("NewBundle() = %+v, want %s holding %d items", got, tt.id, len(tt.contents))
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package pack
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PackLevel names the packs at the bottom of a packaging hierarchy.
+const PackLevel = "pack"
+
+// Level is a layer of containers wrapping the one below it, such as cartons around packs. The capacity
+// of its sizes counts units of the level below whichever size they are, packs for the first level.
+type Level struct {
+	Name  string
+	Sizes Sizes
+}
+
+// Hierarchy lists the levels of packaging of an inventory from the innermost out, pallets after cartons.
+// An empty hierarchy ships bare packs.
+type Hierarchy []Level
+
+// ParseHierarchy reads levels separated by semicolons, each a name followed by the capacities of its
+// containers, such as "carton: 4, 6; pallet: 20". Containers are identified by name and capacity.
+func ParseHierarchy(s string) (Hierarchy, error) {
+	var out Hierarchy
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		name, caps, ok := strings.Cut(part, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("level %q: must be a name followed by capacities, such as carton: 4, 6", part)
+		}
+		if name == PackLevel || slices.ContainsFunc(out, func(l Level) bool { return l.Name == name }) {
+			return nil, fmt.Errorf("level %q: name must be unique and not %q", name, PackLevel)
+		}
+
+		var capacities []int64
+		var labels []string
+		for _, c := range strings.Split(caps, ",") {
+			c = strings.TrimSpace(c)
+			n, err := strconv.ParseInt(c, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("level %q: capacity %q must be a positive number", name, c)
+			}
+			capacities = append(capacities, n)
+			labels = append(labels, name+"-"+c)
+		}
+
+		sizes, err := NewSizes(capacities, labels)
+		if err != nil {
+			return nil, fmt.Errorf("level %q: %w", name, err)
+		}
+		out = append(out, Level{Name: name, Sizes: sizes})
+	}
+	return out, nil
+}
+
+func (h Hierarchy) String() string {
+	levels := make([]string, 0, len(h))
+	for _, l := range h {
+		caps := make([]string, 0, len(l.Sizes))
+		for _, c := range l.Sizes.Capacities() {
+			caps = append(caps, strconv.FormatInt(c, 10))
+		}
+		levels = append(levels, l.Name+": "+strings.Join(caps, ", "))
+	}
+	return strings.Join(levels, "; ")
+}
+
+// Node is Count identical containers of a level holding Items each. Packs are the leaves, their
+// children are left out.
+type Node struct {
+	Level    string `json:"level"`
+	Size     Size   `json:"size"`
+	Count    int64  `json:"count"`
+	Items    Amount `json:"items"`
+	Children []Node `json:"children,omitempty"`
+
+	items int64
+}
+
+// Nest puts the packs into the containers of every level, containers[i] holding the ones of h[i]. Each
+// container takes as many units of the level below as it holds, the largest containers take the largest
+// units first. It returns the outermost containers, or the packs when the hierarchy is empty.
+//
+// Identical containers filled alike are kept as one node counting them, so the tree grows with the sizes
+// used rather than the number of packs.
+func (h Hierarchy) Nest(m Measure, packs Allocations, containers []Allocations) ([]Node, error) {
+	if len(containers) != len(h) {
+		return nil, fmt.Errorf("containers and levels must have the same length")
+	}
+
+	units := runs(PackLevel, packs)
+	for i := range units {
+		units[i].items = units[i].Size.Capacity
+	}
+
+	for i, l := range h {
+		q := queue{nodes: units}
+		var nodes []Node
+		for _, c := range runs(l.Name, containers[i]) {
+			for left := c.Count; left > 0; {
+				n := q.fill(c, left)
+				nodes = append(nodes, n)
+				left -= n.Count
+			}
+		}
+		if left := q.left(); left > 0 {
+			return nil, fmt.Errorf("%d units left over packing into %s", left, l.Name)
+		}
+		units = group(nodes)
+	}
+
+	out := group(units)
+	measure(m, out)
+	return out, nil
+}
+
+// runs lists one node per size of allocs counting its containers, the largest first.
+func runs(level string, allocs Allocations) []Node {
+	sorted := slices.Clone(allocs)
+	slices.SortStableFunc(sorted, func(a, b Allocation) int {
+		return cmp.Or(cmp.Compare(b.Size.Capacity, a.Size.Capacity), cmp.Compare(a.Size.ID, b.Size.ID))
+	})
+
+	var out []Node
+	for _, a := range sorted {
+		if a.Quantity > 0 {
+			out = append(out, Node{Level: level, Size: a.Size, Count: int64(a.Quantity)})
+		}
+	}
+	return out
+}
+
+// queue hands out the units of a level in order, nodes[0] being the next with taken of it packed already.
+type queue struct {
+	nodes []Node
+	taken int64
+}
+
+// fill packs up to count containers like c. As many as the next node fills on its own are packed at once,
+// otherwise a single container takes what it holds from the nodes in order.
+func (q *queue) fill(c Node, count int64) Node {
+	n := c
+	if len(q.nodes) == 0 {
+		n.Count = count
+		return n
+	}
+
+	capacity := c.Size.Capacity
+	if k := min(count, (q.nodes[0].Count-q.taken)/capacity); k > 0 {
+		child := q.nodes[0]
+		child.Count = capacity
+		n.Count, n.Children, n.items = k, []Node{child}, capacity*child.items
+		q.advance(k * capacity)
+		return n
+	}
+
+	n.Count = 1
+	for left := capacity; left > 0 && len(q.nodes) > 0; {
+		child := q.nodes[0]
+		child.Count = min(left, child.Count-q.taken)
+		n.Children = append(n.Children, child)
+		n.items += child.Count * child.items
+		left -= child.Count
+		q.advance(child.Count)
+	}
+	return n
+}
+
+func (q *queue) advance(n int64) {
+	q.taken += n
+	if q.taken == q.nodes[0].Count {
+		q.nodes, q.taken = q.nodes[1:], 0
+	}
+}
+
+// left counts the units not packed yet.
+func (q *queue) left() int64 {
+	out := -q.taken
+	for _, n := range q.nodes {
+		out += n.Count
+	}
+	return out
+}
+
+// group merges runs of identical nodes into one counting them.
+func group(nodes []Node) []Node {
+	var out []Node
+	for _, n := range nodes {
+		if last := len(out) - 1; last >= 0 && out[last].same(n) {
+			out[last].Count += n.Count
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func (n Node) same(other Node) bool {
+	if n.Level != other.Level || n.Size.ID != other.Size.ID || n.items != other.items || len(n.Children) != len(other.Children) {
+		return false
+	}
+	for i := range n.Children {
+		if n.Children[i].Count != other.Children[i].Count || !n.Children[i].same(other.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// measure fills in the items of nodes in the unit of m.
+func measure(m Measure, nodes []Node) {
+	for i := range nodes {
+		nodes[i].Items = Amount{Value: m.Decimal(nodes[i].items), Unit: m.Unit}
+		measure(m, nodes[i].Children)
+	}
+}
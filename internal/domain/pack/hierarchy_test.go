@@ -0,0 +1,96 @@
+package pack
+
+import (
+	"testing"
+)
+
+func TestParseHierarchy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		levels  int
+		wantErr bool
+	}{
+		{input: "", want: "", levels: 0},
+		{input: "carton: 4, 6; pallet: 20", want: "carton: 4, 6; pallet: 20", levels: 2},
+		{input: " carton:6 ; ", want: "carton: 6", levels: 1},
+		{input: "carton", wantErr: true},
+		{input: "carton: 4, 4", wantErr: true},
+		{input: "carton: 0", wantErr: true},
+		{input: "carton: 4; carton: 6", wantErr: true},
+		{input: "pack: 4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseHierarchy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHierarchy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want || len(got) != tt.levels {
+				t.Errorf("ParseHierarchy() = %q with %d levels, want %q with %d", got, len(got), tt.want, tt.levels)
+			}
+		})
+	}
+}
+
+func TestHierarchy_Nest(t *testing.T) {
+	h, err := ParseHierarchy("carton: 2, 3; pallet: 2")
+	if err != nil {
+		t.Fatalf("ParseHierarchy() error = %v", err)
+	}
+	carton2, _ := h[0].Sizes.ByCapacity(2)
+	carton3, _ := h[0].Sizes.ByCapacity(3)
+	pallet, _ := h[1].Sizes.ByCapacity(2)
+
+	small := Size{ID: "S", Label: "S", Capacity: 23}
+	large := Size{ID: "L", Label: "L", Capacity: 53}
+	packs := Allocations{{Size: small, Quantity: 2}, {Size: large, Quantity: 6}}
+	containers := []Allocations{
+		{{Size: carton2, Quantity: 1}, {Size: carton3, Quantity: 2}},
+		{{Size: pallet, Quantity: 2}},
+	}
+
+	got, err := h.Nest(Measure{}, packs, containers)
+	if err != nil {
+		t.Fatalf("Nest() error = %v", err)
+	}
+
+	// Note: the cartons of 3 take the large packs, the carton of 2 the small ones.
+	if len(got) != 2 {
+		t.Fatalf("Nest() = %d pallet groups, want 2", len(got))
+	}
+	full, half := got[0], got[1]
+	if full.Count != 1 || full.Items != Pieces(318) || len(full.Children) != 1 || full.Children[0].Count != 2 {
+		t.Errorf("first pallet = %+v, want two cartons of three large packs", full)
+	}
+	if c := full.Children[0]; c.Size.ID != carton3.ID || len(c.Children) != 1 || c.Children[0].Size.ID != "L" || c.Children[0].Count != 3 {
+		t.Errorf("first pallet carton = %+v, want three large packs", c)
+	}
+	if half.Count != 1 || half.Items != Pieces(46) || len(half.Children) != 1 || half.Children[0].Size.ID != carton2.ID {
+		t.Errorf("second pallet = %+v, want one carton of two small packs", half)
+	}
+
+	// Note: millions of packs nest into a handful of nodes.
+	many := Allocations{{Size: small, Quantity: 6_000_001}}
+	got, err = h.Nest(Measure{}, many, []Allocations{
+		{{Size: carton2, Quantity: 2}, {Size: carton3, Quantity: 1_999_999}},
+		{{Size: pallet, Quantity: 1_000_001}},
+	})
+	if err != nil {
+		t.Fatalf("Nest() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Count != 999_999 || got[0].Items != Pieces(138) || got[1].Items != Pieces(115) || got[2].Items != Pieces(46) {
+		t.Errorf("Nest() = %+v, want 999999 full pallets, one mixed and one holding a carton of 2", got)
+	}
+
+	if _, err := h.Nest(Measure{}, packs, containers[:1]); err == nil {
+		t.Error("Nest() with a level missing, want error")
+	}
+	if _, err := h.Nest(Measure{}, packs, []Allocations{{{Size: carton2, Quantity: 1}}, {{Size: pallet, Quantity: 1}}}); err == nil {
+		t.Error("Nest() with too few containers, want error")
+	}
+}
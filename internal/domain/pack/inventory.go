@@ -4,7 +4,13 @@ type Inventory struct {
 	sku   string
 	packs Sizes
 
-	// TODO: keep track of Stock
+	// trackStock enables enforcing Size.Stock during allocation.
+	trackStock bool
+	tieBreak   TieBreak
+	// algorithm names the allocator to use, empty means the server default.
+	algorithm string
+	measure   Measure
+	hierarchy Hierarchy
 }
 
 func (i *Inventory) SKU() string {
@@ -19,9 +25,52 @@ func NewInventory(sku string, sizes Sizes) *Inventory {
 }
 
 func (i *Inventory) Update(sizes Sizes) {
-	i.packs = sizes
+	i.packs = sizes.WithMeasure(i.measure)
 }
 
 func (i *Inventory) AvailableSizes() Sizes {
 	return i.packs
 }
+
+func (i *Inventory) TrackStock(on bool) {
+	i.trackStock = on
+}
+
+func (i *Inventory) TracksStock() bool {
+	return i.trackStock
+}
+
+func (i *Inventory) SetTieBreak(t TieBreak) {
+	i.tieBreak = t
+}
+
+func (i *Inventory) TieBreak() TieBreak {
+	return i.tieBreak
+}
+
+func (i *Inventory) SetAlgorithm(name string) {
+	i.algorithm = name
+}
+
+func (i *Inventory) Algorithm() string {
+	return i.algorithm
+}
+
+// SetMeasure changes the measure of the inventory, the capacities of its sizes are taken in steps of it.
+func (i *Inventory) SetMeasure(m Measure) {
+	i.measure = m
+	i.packs = i.packs.WithMeasure(m)
+}
+
+func (i *Inventory) Measure() Measure {
+	return i.measure
+}
+
+// SetHierarchy changes the packaging the packs of an allocation are nested in.
+func (i *Inventory) SetHierarchy(h Hierarchy) {
+	i.hierarchy = h
+}
+
+func (i *Inventory) Hierarchy() Hierarchy {
+	return i.hierarchy
+}
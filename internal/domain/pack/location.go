@@ -0,0 +1,67 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Location is a warehouse stocking an inventory, with its own stock of the sizes.
+type Location struct {
+	ID string `json:"id"`
+	// ShippingCost is charged once for an allocation taking any packs from the location, in minor currency units.
+	ShippingCost int64 `json:"shipping_cost"`
+	// Stock is the number of packs of each size on hand at the location.
+	Stock map[ID]Quantity `json:"stock"`
+}
+
+// Sizes returns the sizes with their stock at the location, sizes it does not stock have none.
+func (l Location) Sizes(sizes Sizes) Sizes {
+	out := make(Sizes, len(sizes))
+	for i, s := range sizes {
+		s.Stock = l.Stock[s.ID]
+		out[i] = s
+	}
+	return out
+}
+
+// Locations are the warehouses an inventory is stocked in.
+type Locations []Location
+
+// ByID returns the location with the given ID.
+func (l Locations) ByID(id string) (Location, bool) {
+	for _, loc := range l {
+		if loc.ID == id {
+			return loc, true
+		}
+	}
+	return Location{}, false
+}
+
+// Validate checks the locations have distinct, non-empty IDs, no negative shipping cost and stock only of
+// sizes, never negative.
+func (l Locations) Validate(sizes Sizes) error {
+	var errs []error
+	seen := make(map[string]bool, len(l))
+	for i, loc := range l {
+		switch {
+		case loc.ID == "":
+			errs = append(errs, fmt.Errorf("location %d: id is required", i+1))
+		case seen[loc.ID]:
+			errs = append(errs, fmt.Errorf("location %s: listed twice", loc.ID))
+		}
+		seen[loc.ID] = true
+
+		if loc.ShippingCost < 0 {
+			errs = append(errs, fmt.Errorf("location %s: shipping cost must not be negative", loc.ID))
+		}
+		for id, q := range loc.Stock {
+			if _, ok := sizes.ByID(id); !ok {
+				errs = append(errs, fmt.Errorf("location %s: %w: %s", loc.ID, ErrUnknownSize, id))
+			}
+			if q < 0 {
+				errs = append(errs, fmt.Errorf("location %s: stock of %s must not be negative", loc.ID, id))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
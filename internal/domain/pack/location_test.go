@@ -0,0 +1,61 @@
+package pack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLocations_Validate(t *testing.T) {
+	sizes := Sizes{{ID: "S", Capacity: 23}, {ID: "L", Capacity: 31}}
+
+	tests := []struct {
+		name      string
+		locations Locations
+		wantErr   bool
+		wantIs    error
+	}{
+		{name: "none"},
+		{
+			name: "valid",
+			locations: Locations{
+				{ID: "north", ShippingCost: 500, Stock: map[ID]Quantity{"S": 10}},
+				{ID: "south", Stock: map[ID]Quantity{"S": 1, "L": 2}},
+			},
+		},
+		{name: "missing id", locations: Locations{{}}, wantErr: true},
+		{name: "duplicate", locations: Locations{{ID: "north"}, {ID: "north"}}, wantErr: true},
+		{name: "negative cost", locations: Locations{{ID: "north", ShippingCost: -1}}, wantErr: true},
+		{name: "negative stock", locations: Locations{{ID: "north", Stock: map[ID]Quantity{"S": -1}}}, wantErr: true},
+		{
+			name:      "unknown size",
+			locations: Locations{{ID: "north", Stock: map[ID]Quantity{"XL": 1}}},
+			wantErr:   true,
+			wantIs:    ErrUnknownSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.locations.Validate(sizes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestLocation_Sizes(t *testing.T) {
+	sizes := Sizes{{ID: "S", Capacity: 23, Stock: 100}, {ID: "L", Capacity: 31, Stock: 100}}
+	loc := Location{ID: "north", Stock: map[ID]Quantity{"S": 4}}
+
+	got := loc.Sizes(sizes)
+	if got[0].Stock != 4 || got[1].Stock != 0 {
+		t.Errorf("Sizes() = %+v, want the stock of the location", got)
+	}
+	if sizes[0].Stock != 100 {
+		t.Error("Sizes() changed the sizes it was given")
+	}
+}
@@ -0,0 +1,299 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrIncompatibleUnits is returned when an amount cannot be converted to the unit of a measure.
+	ErrIncompatibleUnits = errors.New("incompatible units")
+	// ErrInvalidAmount is returned for amounts that cannot be represented by a measure.
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// MaxPrecision is the most decimal places a measure or a decimal keeps.
+const MaxPrecision = 6
+
+// Unit is a unit of measure of capacities and demands.
+type Unit string
+
+const (
+	// Piece counts whole items, it is the unit of inventories without a measure.
+	Piece      Unit = ""
+	Gram       Unit = "g"
+	Kilogram   Unit = "kg"
+	Millilitre Unit = "ml"
+	Litre      Unit = "l"
+)
+
+// Units lists the units an inventory can be measured in.
+var Units = []Unit{Piece, Gram, Kilogram, Millilitre, Litre}
+
+// ParseUnit reads a unit symbol, "pcs" and an empty string both mean Piece.
+func ParseUnit(s string) (Unit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "pc", "pcs":
+		return Piece, nil
+	case "g":
+		return Gram, nil
+	case "kg":
+		return Kilogram, nil
+	case "ml":
+		return Millilitre, nil
+	case "l":
+		return Litre, nil
+	default:
+		return "", fmt.Errorf("unknown unit: %q", s)
+	}
+}
+
+// Symbol is the unit as shown next to a number.
+func (u Unit) Symbol() string {
+	if u == Piece {
+		return "pcs"
+	}
+	return string(u)
+}
+
+// base returns the unit amounts of u convert through and how many of it one u is.
+func (u Unit) base() (Unit, int64) {
+	switch u {
+	case Kilogram:
+		return Gram, 1000
+	case Litre:
+		return Millilitre, 1000
+	default:
+		return u, 1
+	}
+}
+
+// Decimal is a fixed-point number, Value divided by ten to the power of Precision.
+type Decimal struct {
+	Value     int64
+	Precision int
+}
+
+// ParseDecimal reads a non-negative number such as "2.5", keeping as many decimal places as it has.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > MaxPrecision {
+		return Decimal{}, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, s, MaxPrecision)
+	}
+	if whole == "" && frac == "" || strings.ContainsAny(whole+frac, "+-") {
+		return Decimal{}, fmt.Errorf("%w: %q must be a non-negative number", ErrInvalidAmount, s)
+	}
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("%w: %q must be a non-negative number", ErrInvalidAmount, s)
+	}
+	return Decimal{Value: v, Precision: len(frac)}, nil
+}
+
+// Sign returns -1, 0 or 1 as the decimal is negative, zero or positive.
+func (d Decimal) Sign() int {
+	switch {
+	case d.Value < 0:
+		return -1
+	case d.Value > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String formats the decimal without trailing zeros, "2.5" rather than "2.500".
+func (d Decimal) String() string {
+	s := strconv.FormatInt(d.Value, 10)
+	if d.Precision <= 0 {
+		return s
+	}
+
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= d.Precision {
+		s = strings.Repeat("0", d.Precision-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-d.Precision], strings.TrimRight(s[len(s)-d.Precision:], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// rat returns the exact value of the decimal.
+func (d Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(d.Value), pow10(d.Precision))
+}
+
+// Amount is a decimal number of a unit such as 2.5 kg. An amount in Piece is taken in the unit of
+// whatever measure scales it, so a bare "500" means 500 kg of an inventory measured in kilograms.
+type Amount struct {
+	Value Decimal
+	Unit  Unit
+}
+
+// Pieces is an amount of n whole items, or of n of the unit of the measure scaling it.
+func Pieces(n int64) Amount {
+	return Amount{Value: Decimal{Value: n}}
+}
+
+// ParseAmount reads a number optionally followed by a unit, such as "2.5 kg", "750ml" or "500".
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i < 0 {
+		i = len(s)
+	}
+
+	value, err := ParseDecimal(s[:i])
+	if err != nil {
+		return Amount{}, err
+	}
+	unit, err := ParseUnit(s[i:])
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{Value: value, Unit: unit}, nil
+}
+
+func (a Amount) String() string {
+	if a.Unit == Piece {
+		return a.Value.String()
+	}
+	return a.Value.String() + " " + string(a.Unit)
+}
+
+// MarshalJSON writes a number for amounts without a unit and a string understood by ParseAmount otherwise.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.Unit == Piece {
+		return a.Value.MarshalJSON()
+	}
+	return json.Marshal(a.String())
+}
+
+func (a *Amount) UnmarshalText(text []byte) error {
+	parsed, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts a number as well as a string understood by ParseAmount.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*a = Amount{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return a.UnmarshalText([]byte(s))
+	}
+	return a.UnmarshalText(data)
+}
+
+// Measure is the unit an inventory is sold in and the decimal places of it the allocator keeps.
+// Capacities and demands are stored scaled to whole steps of the measure, a tenth of a kilogram when
+// the precision is one. The zero value counts whole pieces.
+type Measure struct {
+	Unit      Unit
+	Precision int
+}
+
+func NewMeasure(unit string, precision int) (Measure, error) {
+	u, err := ParseUnit(unit)
+	if err != nil {
+		return Measure{}, err
+	}
+	if precision < 0 || precision > MaxPrecision {
+		return Measure{}, fmt.Errorf("precision must be between 0 and %d", MaxPrecision)
+	}
+	if u == Piece && precision > 0 {
+		return Measure{}, fmt.Errorf("pieces cannot have decimal places")
+	}
+	return Measure{Unit: u, Precision: precision}, nil
+}
+
+// Step is the number of scaled units in one unit of the measure.
+func (m Measure) Step() int64 {
+	return pow10(m.Precision).Int64()
+}
+
+// Scale converts an amount to whole steps of the measure, rounding up what falls between two steps.
+func (m Measure) Scale(a Amount) (int64, error) {
+	return m.scale(a, false)
+}
+
+// ScaleExact converts an amount to whole steps of the measure and fails when it falls between two steps.
+func (m Measure) ScaleExact(a Amount) (int64, error) {
+	return m.scale(a, true)
+}
+
+func (m Measure) scale(a Amount, exact bool) (int64, error) {
+	unit := a.Unit
+	if unit == Piece {
+		unit = m.Unit
+	}
+	from, fromFactor := unit.base()
+	to, toFactor := m.Unit.base()
+	if from != to {
+		return 0, fmt.Errorf("%w: %s to %s", ErrIncompatibleUnits, unit.Symbol(), m.Unit.Symbol())
+	}
+
+	// Note: value * fromFactor / toFactor is the amount in the unit of the measure, Step scales it.
+	r := a.Value.rat()
+	r.Mul(r, new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(fromFactor), pow10(m.Precision)), big.NewInt(toFactor)))
+
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		if exact {
+			return 0, fmt.Errorf("%w: %s is finer than %s", ErrInvalidAmount, a, m.Format(1))
+		}
+		if rem.Sign() > 0 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %s is too large", ErrInvalidAmount, a)
+	}
+	return q.Int64(), nil
+}
+
+// Decimal returns n steps of the measure in its unit.
+func (m Measure) Decimal(n int64) Decimal {
+	return Decimal{Value: n, Precision: m.Precision}
+}
+
+// Format shows n steps of the measure along with its unit, such as "2.5 kg" or "23 pcs".
+func (m Measure) Format(n int64) string {
+	return m.Decimal(n).String() + " " + m.Unit.Symbol()
+}
+
+func (m Measure) String() string {
+	if m.Precision == 0 {
+		return m.Unit.Symbol()
+	}
+	return fmt.Sprintf("%s (%d decimal places)", m.Unit.Symbol(), m.Precision)
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,156 @@
+package pack
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Amount
+		wantErr bool
+	}{
+		{input: "500", want: Amount{Value: Decimal{Value: 500}}},
+		{input: "2.5 kg", want: Amount{Value: Decimal{Value: 25, Precision: 1}, Unit: Kilogram}},
+		{input: "750ml", want: Amount{Value: Decimal{Value: 750}, Unit: Millilitre}},
+		{input: "0.75 L", want: Amount{Value: Decimal{Value: 75, Precision: 2}, Unit: Litre}},
+		{input: "12 pcs", want: Amount{Value: Decimal{Value: 12}}},
+		{input: ".5", want: Amount{Value: Decimal{Value: 5, Precision: 1}}},
+		{input: "", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "2.5 oz", wantErr: true},
+		{input: "1.0000001", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAmount(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimal_String(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{d: Decimal{Value: 23}, want: "23"},
+		{d: Decimal{Value: 25, Precision: 1}, want: "2.5"},
+		{d: Decimal{Value: 2500, Precision: 3}, want: "2.5"},
+		{d: Decimal{Value: 3000, Precision: 3}, want: "3"},
+		{d: Decimal{Value: 5, Precision: 3}, want: "0.005"},
+		{d: Decimal{Value: -75, Precision: 2}, want: "-0.75"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestMeasure_Scale(t *testing.T) {
+	kg := Measure{Unit: Kilogram, Precision: 1}
+
+	tests := []struct {
+		name      string
+		measure   Measure
+		amount    string
+		want      int64
+		wantExact bool
+		wantErr   error
+	}{
+		{name: "measure unit", measure: kg, amount: "2.5 kg", want: 25, wantExact: true},
+		{name: "bare number", measure: kg, amount: "100", want: 1000, wantExact: true},
+		{name: "grams", measure: kg, amount: "2500 g", want: 25, wantExact: true},
+		{name: "rounded up", measure: kg, amount: "2501 g", want: 26},
+		{name: "litres to millilitres", measure: Measure{Unit: Millilitre}, amount: "0.75 l", want: 750, wantExact: true},
+		{name: "pieces", measure: Measure{}, amount: "23", want: 23, wantExact: true},
+		{name: "incompatible", measure: kg, amount: "1 l", wantErr: ErrIncompatibleUnits},
+		{name: "mass of pieces", measure: Measure{}, amount: "1 kg", wantErr: ErrIncompatibleUnits},
+		{name: "too large", measure: Measure{Unit: Gram, Precision: 6}, amount: "9223372036854775807 kg", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAmount(tt.amount)
+			if err != nil {
+				t.Fatalf("ParseAmount() error = %v", err)
+			}
+
+			got, err := tt.measure.Scale(a)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Scale() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Scale() = %d, want %d", got, tt.want)
+			}
+
+			_, err = tt.measure.ScaleExact(a)
+			if (err == nil) != tt.wantExact {
+				t.Errorf("ScaleExact() error = %v, want exact %v", err, tt.wantExact)
+			}
+		})
+	}
+}
+
+func TestNewMeasure(t *testing.T) {
+	if _, err := NewMeasure("pcs", 1); err == nil {
+		t.Error("NewMeasure() with decimal pieces, want error")
+	}
+	if _, err := NewMeasure("kg", MaxPrecision+1); err == nil {
+		t.Error("NewMeasure() above the maximum precision, want error")
+	}
+	m, err := NewMeasure("L", 2)
+	if err != nil || m != (Measure{Unit: Litre, Precision: 2}) {
+		t.Errorf("NewMeasure() = %v, %v", m, err)
+	}
+	if got := m.Format(75); got != "0.75 l" {
+		t.Errorf("Format() = %q, want %q", got, "0.75 l")
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var got []Amount
+	if err := json.Unmarshal([]byte(`[500, 2.5, "750 ml"]`), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := []Amount{
+		{Value: Decimal{Value: 500}},
+		{Value: Decimal{Value: 25, Precision: 1}},
+		{Value: Decimal{Value: 750}, Unit: Millilitre},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Unmarshal()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	out, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(out) != `[500,2.5,"750 ml"]` {
+		t.Errorf("Marshal() = %s", out)
+	}
+}
+
+func TestSize_MarshalJSON(t *testing.T) {
+	s := Size{ID: "bag", Capacity: 25, Label: "bag", Measure: Measure{Unit: Kilogram, Precision: 1}}
+	out, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"ID":"bag","Capacity":2.5,"Unit":"kg","Label":"bag","Stock":0,"Price":0,"MinQuantity":0,"Multiple":0,"Required":false}`
+	if string(out) != want {
+		t.Errorf("Marshal() = %s, want %s", out, want)
+	}
+}
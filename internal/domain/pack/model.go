@@ -1,26 +1,165 @@
 package pack
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 )
 
+// ErrUnknownSize is returned when a size ID does not belong to the sizes.
+var ErrUnknownSize = errors.New("unknown size")
+
 type ID string
 
 type Size struct {
 	ID       ID
 	Capacity int64
 	Label    string
+	// Stock is the number of packs of this size on hand.
+	// It is only enforced when the inventory tracks stock.
+	Stock Quantity
+	// Price is the cost of a single pack in minor currency units.
+	Price int64
+	// MinQuantity is the least number of packs of this size an allocation may use, if it uses any.
+	MinQuantity Quantity
+	// Multiple forces the number of packs of this size to be a multiple of it, zero means any.
+	Multiple Quantity
+	// Required makes an allocation use at least one pack of this size.
+	// It is set per request and never stored with the inventory.
+	Required bool
+	// Measure is the measure of the inventory, Capacity is in whole steps of it.
+	Measure Measure
+	// Weight of a single pack in grams and its outer Dimensions, zero when unknown.
+	Weight     int64
+	Dimensions Dimensions
+}
+
+// Dimensions are the outer measurements of a pack in centimetres.
+type Dimensions struct {
+	Length int64 `json:"length"`
+	Width  int64 `json:"width"`
+	Height int64 `json:"height"`
+}
+
+// ParseDimensions reads length, width and height separated by "x", such as "60x60x20".
+// An empty string means unknown dimensions.
+func ParseDimensions(s string) (Dimensions, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Dimensions{}, nil
+	}
+
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) != 3 {
+		return Dimensions{}, fmt.Errorf("dimensions %q: must be length x width x height", s)
+	}
+	var out [3]int64
+	for i, p := range parts {
+		n, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
+		if err != nil || n <= 0 {
+			return Dimensions{}, fmt.Errorf("dimensions %q: must be positive whole centimetres", s)
+		}
+		out[i] = n
+	}
+	return Dimensions{Length: out[0], Width: out[1], Height: out[2]}, nil
+}
+
+// Volume is in cubic centimetres.
+func (d Dimensions) Volume() int64 {
+	return d.Length * d.Width * d.Height
+}
+
+func (d Dimensions) String() string {
+	if d == (Dimensions{}) {
+		return ""
+	}
+	return fmt.Sprintf("%dx%dx%d", d.Length, d.Width, d.Height)
+}
+
+// Volume of a single pack in cubic centimetres, zero when its dimensions are unknown.
+func (s Size) Volume() int64 {
+	return s.Dimensions.Volume()
+}
+
+// Amount is the capacity of the size in the unit of its measure.
+func (s Size) Amount() Amount {
+	return Amount{Value: s.Measure.Decimal(s.Capacity), Unit: s.Measure.Unit}
+}
+
+// MarshalJSON writes the capacity in the unit of the measure, the unit is left out for pieces.
+func (s Size) MarshalJSON() ([]byte, error) {
+	var dimensions *Dimensions
+	if s.Dimensions != (Dimensions{}) {
+		dimensions = &s.Dimensions
+	}
+	return json.Marshal(struct {
+		ID          ID
+		Capacity    Decimal
+		Unit        Unit `json:",omitempty"`
+		Label       string
+		Stock       Quantity
+		Price       int64
+		MinQuantity Quantity
+		Multiple    Quantity
+		Required    bool
+		Weight      int64       `json:",omitempty"`
+		Dimensions  *Dimensions `json:",omitempty"`
+	}{
+		ID:          s.ID,
+		Capacity:    s.Measure.Decimal(s.Capacity),
+		Unit:        s.Measure.Unit,
+		Label:       s.Label,
+		Stock:       s.Stock,
+		Price:       s.Price,
+		MinQuantity: s.MinQuantity,
+		Multiple:    s.Multiple,
+		Required:    s.Required,
+		Weight:      s.Weight,
+		Dimensions:  dimensions,
+	})
+}
+
+// Constrained reports whether the size limits the quantities it can be allocated in.
+func (s Size) Constrained() bool {
+	return s.MinQuantity > 1 || s.Multiple > 1 || s.Required
+}
+
+// Allows reports whether q packs of this size satisfy its constraints.
+func (s Size) Allows(q Quantity) bool {
+	if q == 0 {
+		return !s.Required
+	}
+	if q < s.MinQuantity {
+		return false
+	}
+	return s.Multiple <= 1 || q%s.Multiple == 0
+}
+
+// Constraint limits the quantities a size can be allocated in.
+type Constraint struct {
+	MinQuantity Quantity
+	Multiple    Quantity
 }
 
 type Sizes []Size
 
-func NewSizes(capacities []int64, labels []string) (Sizes, error) {
+// NewSizes builds sizes from capacities and labels, matched by index. Capacities must be positive.
+// Constraints are optional, when given there must be one per capacity and the fewest packs they allow
+// must hold a countable number of items.
+func NewSizes(capacities []int64, labels []string, constraints ...Constraint) (Sizes, error) {
 	if len(capacities) != len(labels) {
 		return nil, fmt.Errorf("capacities and labels must have the same length")
 	}
 	if len(capacities) == 0 {
 		return nil, fmt.Errorf("capacities and labels must have at least one element")
 	}
+	if len(constraints) > 0 && len(constraints) != len(capacities) {
+		return nil, fmt.Errorf("constraints and capacities must have the same length")
+	}
 
 	if hasDuplicates(capacities) {
 		return nil, fmt.Errorf("capacities must not have duplicates")
@@ -28,16 +167,146 @@ func NewSizes(capacities []int64, labels []string) (Sizes, error) {
 
 	out := make(Sizes, len(capacities))
 	for i, c := range capacities {
+		if c <= 0 {
+			return nil, fmt.Errorf("capacity of %s must be positive", labels[i])
+		}
 		out[i] = Size{
 			ID:       ID(labels[i]),
 			Capacity: c,
 			Label:    labels[i],
 		}
+		if len(constraints) == 0 {
+			continue
+		}
+
+		con := constraints[i]
+		if con.MinQuantity < 0 {
+			return nil, fmt.Errorf("minimum quantity of %s must not be negative", labels[i])
+		}
+		if con.Multiple < 0 {
+			return nil, fmt.Errorf("multiple of %s must not be negative", labels[i])
+		}
+		// Note: the fewest packs an allocation can take is the minimum rounded up to the multiple, it has to be
+		// countable in items.
+		least := max(con.MinQuantity, 1)
+		if con.Multiple > 1 {
+			least = (least + con.Multiple - 1) / con.Multiple * con.Multiple
+		}
+		if least < con.MinQuantity || least > Quantity(math.MaxInt64/c) {
+			return nil, fmt.Errorf("minimum quantity %d of %s in multiples of %d is more packs than can be allocated", con.MinQuantity, labels[i], con.Multiple)
+		}
+		out[i].MinQuantity = con.MinQuantity
+		out[i].Multiple = con.Multiple
+	}
+
+	return out, nil
+}
+
+// WithStock returns a copy of sizes with the on-hand stock set, matched by index.
+func (s Sizes) WithStock(stock []int64) (Sizes, error) {
+	if len(stock) != len(s) {
+		return nil, fmt.Errorf("stock and sizes must have the same length")
+	}
+
+	out := make(Sizes, len(s))
+	for i, size := range s {
+		if stock[i] < 0 {
+			return nil, fmt.Errorf("stock must not be negative")
+		}
+		size.Stock = Quantity(stock[i])
+		out[i] = size
+	}
+
+	return out, nil
+}
+
+// WithPrices returns a copy of sizes with the pack prices set, matched by index.
+func (s Sizes) WithPrices(prices []int64) (Sizes, error) {
+	if len(prices) != len(s) {
+		return nil, fmt.Errorf("prices and sizes must have the same length")
+	}
+
+	out := make(Sizes, len(s))
+	for i, size := range s {
+		if prices[i] < 0 {
+			return nil, fmt.Errorf("price must not be negative")
+		}
+		size.Price = prices[i]
+		out[i] = size
+	}
+
+	return out, nil
+}
+
+// WithPhysical returns a copy of sizes with the pack weights and dimensions set, matched by index.
+func (s Sizes) WithPhysical(weights []int64, dimensions []Dimensions) (Sizes, error) {
+	if len(weights) != len(s) || len(dimensions) != len(s) {
+		return nil, fmt.Errorf("weights, dimensions and sizes must have the same length")
+	}
+
+	out := make(Sizes, len(s))
+	for i, size := range s {
+		if weights[i] < 0 {
+			return nil, fmt.Errorf("weight must not be negative")
+		}
+		size.Weight = weights[i]
+		size.Dimensions = dimensions[i]
+		out[i] = size
+	}
+
+	return out, nil
+}
+
+// WithMeasure returns a copy of sizes measured by m.
+func (s Sizes) WithMeasure(m Measure) Sizes {
+	out := make(Sizes, len(s))
+	for i, size := range s {
+		size.Measure = m
+		out[i] = size
+	}
+	return out
+}
+
+// Without returns a copy of sizes without the ones listed in ids.
+func (s Sizes) Without(ids []ID) (Sizes, error) {
+	if err := s.known(ids); err != nil {
+		return nil, err
+	}
+
+	out := make(Sizes, 0, len(s))
+	for _, size := range s {
+		if !slices.Contains(ids, size.ID) {
+			out = append(out, size)
+		}
+	}
+
+	return out, nil
+}
+
+// Requiring returns a copy of sizes where the ones listed in ids must be used by an allocation.
+func (s Sizes) Requiring(ids []ID) (Sizes, error) {
+	if err := s.known(ids); err != nil {
+		return nil, err
+	}
+
+	out := make(Sizes, len(s))
+	for i, size := range s {
+		size.Required = size.Required || slices.Contains(ids, size.ID)
+		out[i] = size
 	}
 
 	return out, nil
 }
 
+func (s Sizes) known(ids []ID) error {
+	for _, id := range ids {
+		if _, ok := s.ByID(id); !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownSize, id)
+		}
+	}
+	return nil
+}
+
 func (s Sizes) Combine(other Sizes) (Sizes, error) {
 	if len(s) == 0 {
 		return other, nil
@@ -73,6 +342,16 @@ func hasDuplicates(capacities []int64) bool {
 	return false
 }
 
+// Constrained reports whether any of the sizes limits the quantities it can be allocated in.
+func (s Sizes) Constrained() bool {
+	for _, s := range s {
+		if s.Constrained() {
+			return true
+		}
+	}
+	return false
+}
+
 func (s Sizes) ByID(id ID) (Size, bool) {
 	for _, s := range s {
 		if s.ID == id {
@@ -99,6 +378,22 @@ func (s Sizes) Capacities() []int64 {
 	return out
 }
 
+func (s Sizes) Stocks() []int64 {
+	out := make([]int64, 0, len(s))
+	for _, s := range s {
+		out = append(out, int64(s.Stock))
+	}
+	return out
+}
+
+func (s Sizes) Prices() []int64 {
+	out := make([]int64, 0, len(s))
+	for _, s := range s {
+		out = append(out, s.Price)
+	}
+	return out
+}
+
 type Quantity int64
 
 type Allocation struct {
@@ -123,3 +418,46 @@ func (a Allocations) SumPacks() int64 {
 	}
 	return out
 }
+
+// TotalCost adds up the price of every pack, saturating at math.MaxInt64 rather than wrapping around.
+func (a Allocations) TotalCost() int64 {
+	out := int64(0)
+	for _, a := range a {
+		out = AddSat(out, MulSat(int64(a.Quantity), a.Size.Price))
+	}
+	return out
+}
+
+// TotalWeight adds up the weight of every pack in grams, saturating like TotalCost.
+func (a Allocations) TotalWeight() int64 {
+	out := int64(0)
+	for _, a := range a {
+		out = AddSat(out, MulSat(int64(a.Quantity), a.Size.Weight))
+	}
+	return out
+}
+
+// TotalVolume adds up the volume of every pack in cubic centimetres, saturating like TotalCost.
+func (a Allocations) TotalVolume() int64 {
+	out := int64(0)
+	for _, a := range a {
+		out = AddSat(out, MulSat(int64(a.Quantity), a.Size.Volume()))
+	}
+	return out
+}
+
+// AddSat adds two non-negative totals, saturating at math.MaxInt64 rather than wrapping around.
+func AddSat(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// MulSat multiplies two non-negative totals, saturating at math.MaxInt64 rather than wrapping around.
+func MulSat(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
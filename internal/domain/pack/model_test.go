@@ -1,6 +1,8 @@
 package pack
 
 import (
+	"fmt"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -45,6 +47,20 @@ func TestNewSizes(t *testing.T) {
 			want:       nil,
 			wantErr:    true,
 		},
+		{
+			name:       "zero capacity",
+			capacities: []int64{0, 30},
+			labels:     []string{"empty", "large"},
+			want:       nil,
+			wantErr:    true,
+		},
+		{
+			name:       "negative capacity",
+			capacities: []int64{-10, 30},
+			labels:     []string{"small", "large"},
+			want:       nil,
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -61,6 +77,94 @@ func TestNewSizes(t *testing.T) {
 	}
 }
 
+func TestNewSizes_Constraints(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints []Constraint
+		want        Sizes
+		wantErr     bool
+	}{
+		{
+			name:        "valid constraints",
+			constraints: []Constraint{{MinQuantity: 10}, {Multiple: 6}},
+			want: Sizes{
+				{ID: "small", Capacity: 10, Label: "small", MinQuantity: 10},
+				{ID: "large", Capacity: 30, Label: "large", Multiple: 6},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "different lengths",
+			constraints: []Constraint{{MinQuantity: 10}},
+			want:        nil,
+			wantErr:     true,
+		},
+		{
+			name:        "negative minimum",
+			constraints: []Constraint{{MinQuantity: -1}, {}},
+			want:        nil,
+			wantErr:     true,
+		},
+		{
+			name:        "negative multiple",
+			constraints: []Constraint{{}, {Multiple: -6}},
+			want:        nil,
+			wantErr:     true,
+		},
+		{
+			name:        "minimum beyond the items countable",
+			constraints: []Constraint{{MinQuantity: math.MaxInt64 / 5}, {}},
+			want:        nil,
+			wantErr:     true,
+		},
+		{
+			name:        "minimum rounded up to the multiple overflows",
+			constraints: []Constraint{{}, {MinQuantity: math.MaxInt64 - 1, Multiple: 4}},
+			want:        nil,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSizes([]int64{10, 30}, []string{"small", "large"}, tt.constraints...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSizes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewSizes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSize_Allows(t *testing.T) {
+	size := Size{MinQuantity: 10, Multiple: 6}
+
+	tests := []struct {
+		quantity Quantity
+		want     bool
+	}{
+		{quantity: 0, want: true},
+		{quantity: 6, want: false},
+		{quantity: 11, want: false},
+		{quantity: 12, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.quantity), func(t *testing.T) {
+			if got := size.Allows(tt.quantity); got != tt.want {
+				t.Errorf("Allows(%d) = %v, want %v", tt.quantity, got, tt.want)
+			}
+		})
+	}
+
+	if (Size{Required: true}).Allows(0) {
+		t.Errorf("Allows(0) = true for a required size, want false")
+	}
+}
+
 func TestSizes_Combine(t *testing.T) {
 	s1 := Sizes{{ID: "small", Capacity: 10, Label: "small"}}
 	s2 := Sizes{{ID: "large", Capacity: 20, Label: "large"}}
@@ -120,6 +224,195 @@ func TestSizes_Combine(t *testing.T) {
 	}
 }
 
+func TestSizes_WithStock(t *testing.T) {
+	sizes := Sizes{
+		{ID: "small", Capacity: 10, Label: "small"},
+		{ID: "medium", Capacity: 20, Label: "medium"},
+	}
+
+	tests := []struct {
+		name    string
+		stock   []int64
+		want    Sizes
+		wantErr bool
+	}{
+		{
+			name:  "valid stock",
+			stock: []int64{5, 0},
+			want: Sizes{
+				{ID: "small", Capacity: 10, Label: "small", Stock: 5},
+				{ID: "medium", Capacity: 20, Label: "medium", Stock: 0},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "different lengths",
+			stock:   []int64{5},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "negative stock",
+			stock:   []int64{5, -1},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sizes.WithStock(tt.stock)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithStock() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithStock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizes_Without(t *testing.T) {
+	sizes := Sizes{
+		{ID: "small", Capacity: 10, Label: "small"},
+		{ID: "medium", Capacity: 20, Label: "medium"},
+	}
+
+	tests := []struct {
+		name    string
+		ids     []ID
+		want    Sizes
+		wantErr bool
+	}{
+		{
+			name: "exclude one",
+			ids:  []ID{"small"},
+			want: Sizes{
+				{ID: "medium", Capacity: 20, Label: "medium"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "exclude none",
+			ids:     nil,
+			want:    sizes,
+			wantErr: false,
+		},
+		{
+			name:    "unknown size",
+			ids:     []ID{"large"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sizes.Without(tt.ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Without() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Without() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizes_Requiring(t *testing.T) {
+	sizes := Sizes{
+		{ID: "small", Capacity: 10, Label: "small"},
+		{ID: "medium", Capacity: 20, Label: "medium"},
+	}
+
+	tests := []struct {
+		name    string
+		ids     []ID
+		want    Sizes
+		wantErr bool
+	}{
+		{
+			name: "require one",
+			ids:  []ID{"medium"},
+			want: Sizes{
+				{ID: "small", Capacity: 10, Label: "small"},
+				{ID: "medium", Capacity: 20, Label: "medium", Required: true},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown size",
+			ids:     []ID{"large"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sizes.Requiring(tt.ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Requiring() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Requiring() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizes_WithPrices(t *testing.T) {
+	sizes := Sizes{
+		{ID: "small", Capacity: 10, Label: "small"},
+		{ID: "medium", Capacity: 20, Label: "medium"},
+	}
+
+	tests := []struct {
+		name    string
+		prices  []int64
+		want    Sizes
+		wantErr bool
+	}{
+		{
+			name:   "valid prices",
+			prices: []int64{100, 150},
+			want: Sizes{
+				{ID: "small", Capacity: 10, Label: "small", Price: 100},
+				{ID: "medium", Capacity: 20, Label: "medium", Price: 150},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "different lengths",
+			prices:  []int64{100},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "negative price",
+			prices:  []int64{100, -1},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sizes.WithPrices(tt.prices)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithPrices() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithPrices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSizes_ByID(t *testing.T) {
 	sizes := Sizes{
 		{ID: "small", Capacity: 10, Label: "small"},
@@ -257,3 +550,136 @@ func TestAllocations_SumPacks(t *testing.T) {
 		})
 	}
 }
+
+func TestAllocations_TotalCost(t *testing.T) {
+	tests := []struct {
+		name        string
+		allocations Allocations
+		want        int64
+	}{
+		{
+			name: "multiple allocations",
+			allocations: Allocations{
+				{Size: Size{Price: 100}, Quantity: 2},
+				{Size: Size{Price: 250}, Quantity: 3},
+			},
+			want: 950, // (100 * 2) + (250 * 3)
+		},
+		{
+			name:        "empty allocations",
+			allocations: Allocations{},
+			want:        0,
+		},
+		{
+			name: "price of a huge demand saturates",
+			allocations: Allocations{
+				{Size: Size{Capacity: 1, Price: 10}, Quantity: 9e18},
+			},
+			want: math.MaxInt64,
+		},
+		{
+			name: "sum of huge demands saturates",
+			allocations: Allocations{
+				{Size: Size{Capacity: 1, Price: 1}, Quantity: 5e18},
+				{Size: Size{Capacity: 2, Price: 1}, Quantity: 5e18},
+			},
+			want: math.MaxInt64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.allocations.TotalCost(); got != tt.want {
+				t.Errorf("TotalCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTieBreak(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		priority []ID
+		want     TieBreak
+		wantErr  bool
+	}{
+		{
+			name: "empty rule prefers larger",
+			rule: "",
+			want: TieBreak{Rule: PreferLarger},
+		},
+		{
+			name:     "priority list is dropped for other rules",
+			rule:     "fewer_sizes",
+			priority: []ID{"small"},
+			want:     TieBreak{Rule: PreferFewerSizes},
+		},
+		{
+			name:     "priority",
+			rule:     "priority",
+			priority: []ID{"small"},
+			want:     TieBreak{Rule: PreferPriority, Priority: []ID{"small"}},
+		},
+		{
+			name:    "priority without sizes",
+			rule:    "priority",
+			wantErr: true,
+		},
+		{
+			name:    "unknown rule",
+			rule:    "smaller",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTieBreak(tt.rule, tt.priority)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTieBreak() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewTieBreak() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTieBreak_Order(t *testing.T) {
+	sizes := Sizes{
+		{ID: "medium", Capacity: 20},
+		{ID: "small", Capacity: 10},
+		{ID: "large", Capacity: 30},
+	}
+
+	tests := []struct {
+		name     string
+		tieBreak TieBreak
+		want     []ID
+	}{
+		{
+			name:     "larger first",
+			tieBreak: TieBreak{Rule: PreferLarger},
+			want:     []ID{"large", "medium", "small"},
+		},
+		{
+			name:     "priority first, then larger",
+			tieBreak: TieBreak{Rule: PreferPriority, Priority: []ID{"small", "unknown"}},
+			want:     []ID{"small", "large", "medium"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ID
+			for _, s := range tt.tieBreak.Order(sizes) {
+				got = append(got, s.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownInventory is returned when no inventory has the SKU asked for.
+var ErrUnknownInventory = errors.New("unknown inventory")
+
+// OrderLine asks for a quantity of one SKU, in the measure of its inventory unless it names a unit.
+type OrderLine struct {
+	SKU      string `json:"sku"`
+	Quantity Amount `json:"quantity"`
+}
+
+// Order is several lines allocated together, each for a different SKU.
+type Order struct {
+	Lines []OrderLine `json:"lines"`
+}
+
+// NewOrder checks every line has a SKU not repeated by another line and a positive quantity, reporting
+// the problems of all lines at once.
+func NewOrder(lines []OrderLine) (Order, error) {
+	if len(lines) == 0 {
+		return Order{}, errors.New("order has no lines")
+	}
+
+	var errs []error
+	seen := make(map[string]int, len(lines))
+	for i, line := range lines {
+		switch first, dup := seen[line.SKU]; {
+		case line.SKU == "":
+			errs = append(errs, fmt.Errorf("line %d: sku is required", i+1))
+		case dup:
+			errs = append(errs, fmt.Errorf("line %d: sku %s is already on line %d", i+1, line.SKU, first+1))
+		default:
+			seen[line.SKU] = i
+		}
+		if line.Quantity.Value.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("line %d: quantity must be positive", i+1))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Order{}, err
+	}
+
+	return Order{Lines: lines}, nil
+}
@@ -0,0 +1,52 @@
+package pack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []OrderLine
+		wantErr []string
+	}{
+		{
+			name:  "valid",
+			lines: []OrderLine{{SKU: "tires", Quantity: Pieces(500)}, {SKU: "rims", Quantity: Pieces(120)}},
+		},
+		{
+			name:    "no lines",
+			wantErr: []string{"no lines"},
+		},
+		{
+			name: "every problem reported",
+			lines: []OrderLine{
+				{SKU: "tires", Quantity: Pieces(500)},
+				{SKU: "", Quantity: Pieces(1)},
+				{SKU: "tires", Quantity: Pieces(0)},
+			},
+			wantErr: []string{"line 2: sku is required", "line 3: sku tires is already on line 1", "line 3: quantity must be positive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewOrder(tt.lines)
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("NewOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("NewOrder() error = %v, want it to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if len(got.Lines) != len(tt.lines) {
+				t.Errorf("NewOrder() = %d lines, want %d", len(got.Lines), len(tt.lines))
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package pack
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// TieRule names how allocations with the same overfill and pack count are told apart.
+type TieRule string
+
+const (
+	// PreferLarger uses as many of the largest packs as possible, then the next largest, and so on.
+	PreferLarger TieRule = "larger"
+	// PreferFewerSizes uses as few distinct sizes as possible, then prefers larger packs.
+	PreferFewerSizes TieRule = "fewer_sizes"
+	// PreferPriority uses as many packs as possible in the order of TieBreak.Priority,
+	// sizes missing from the list come after it, larger first.
+	PreferPriority TieRule = "priority"
+)
+
+// TieBreak is the policy an inventory uses to pick between equally good allocations.
+// The zero value prefers larger packs.
+type TieBreak struct {
+	Rule     TieRule
+	Priority []ID
+}
+
+func NewTieBreak(rule string, priority []ID) (TieBreak, error) {
+	switch TieRule(rule) {
+	case "":
+		rule = string(PreferLarger)
+	case PreferLarger, PreferFewerSizes:
+	case PreferPriority:
+		if len(priority) == 0 {
+			return TieBreak{}, fmt.Errorf("priority tie break needs at least one size")
+		}
+	default:
+		return TieBreak{}, fmt.Errorf("unknown tie break rule: %q", rule)
+	}
+
+	if TieRule(rule) != PreferPriority {
+		priority = nil
+	}
+
+	return TieBreak{
+		Rule:     TieRule(rule),
+		Priority: priority,
+	}, nil
+}
+
+// Order returns sizes from the most to the least preferred, independent of their order in s.
+func (t TieBreak) Order(s Sizes) Sizes {
+	rank := make(map[ID]int, len(t.Priority))
+	if t.Rule == PreferPriority {
+		for i, id := range t.Priority {
+			if _, ok := rank[id]; !ok {
+				rank[id] = i
+			}
+		}
+	}
+
+	out := make(Sizes, len(s))
+	copy(out, s)
+	slices.SortStableFunc(out, func(a, b Size) int {
+		ra, aok := rank[a.ID]
+		rb, bok := rank[b.ID]
+		switch {
+		case aok && bok:
+			return ra - rb
+		case aok:
+			return -1
+		case bok:
+			return 1
+		}
+		return cmp.Compare(b.Capacity, a.Capacity)
+	})
+
+	return out
+}
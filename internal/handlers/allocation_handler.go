@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/IAmRadek/packing/internal/algorithms"
 	"github.com/IAmRadek/packing/internal/app/allocation"
+	"github.com/IAmRadek/packing/internal/domain/pack"
 )
 
 type AllocationHandler struct {
@@ -18,8 +22,48 @@ func NewAllocationHandler(srv *allocation.Service) *AllocationHandler {
 }
 
 type AllocateRequest struct {
-	Sku      string `json:"sku"`
-	Quantity int64  `json:"quantity"`
+	Sku string `json:"sku"`
+	// Quantity is a number in the measure of the inventory, or a string with a unit such as "2.5 kg".
+	Quantity  pack.Amount `json:"quantity"`
+	Objective string      `json:"objective"`
+	// Exclude and Require list size IDs the allocation must not use or must use at least once.
+	Exclude []pack.ID `json:"exclude"`
+	Require []pack.ID `json:"require"`
+	// MaxOverfill is a number of units or a percentage such as "5%", 0 only accepts exact fills.
+	MaxOverfill algorithms.Tolerance `json:"max_overfill"`
+	// Alternatives also lists the non-dominated trade-offs.
+	Alternatives bool `json:"alternatives"`
+	// Algorithm overrides the allocator picked by the inventory.
+	Algorithm string `json:"algorithm"`
+	// Nested also packs the packs into the packaging hierarchy of the inventory.
+	Nested bool `json:"nested"`
+	// Shipments splits the packs into shipments within its limits, or the server ones when it caps nothing.
+	Shipments *algorithms.ShipmentLimits `json:"shipments"`
+	// Partial ships what the stock covers when it cannot cover the quantity and backorders the rest.
+	Partial bool `json:"partial"`
+	// Sourcing takes the packs from the stock of the locations of the inventory for the lowest cost.
+	Sourcing bool `json:"sourcing"`
+}
+
+// AllocateResponse explains the allocation along with the objective it was optimized for.
+type AllocateResponse struct {
+	Objective algorithms.Objective `json:"objective"`
+	allocation.Result
+	Alternatives []allocation.Alternative `json:"alternatives,omitempty"`
+	// Levels and Tree are set for nested requests.
+	Levels []allocation.LevelResult `json:"levels,omitempty"`
+	Tree   []pack.Node              `json:"tree,omitempty"`
+	// ShipmentLimits, Shipments and FewestShipments are set for requests split into shipments.
+	ShipmentLimits  *algorithms.ShipmentLimits `json:"shipment_limits,omitempty"`
+	Shipments       []allocation.Shipment      `json:"shipments,omitempty"`
+	FewestShipments bool                       `json:"fewest_shipments,omitempty"`
+	// Shortfall and Backorder are set for partial requests, the backorder only when the stock falls short.
+	Shortfall *pack.Decimal      `json:"shortfall,omitempty"`
+	Backorder *allocation.Result `json:"backorder,omitempty"`
+	// Locations, ShippingCost and Cost are set for sourcing requests, Cost adds shipping to the packs.
+	Locations    []allocation.LocationResult `json:"locations,omitempty"`
+	ShippingCost *int64                      `json:"shipping_cost,omitempty"`
+	Cost         *int64                      `json:"cost,omitempty"`
 }
 
 func (h *AllocationHandler) HandleAllocate(w http.ResponseWriter, r *http.Request) {
@@ -34,16 +78,380 @@ func (h *AllocationHandler) HandleAllocate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if req.Quantity <= 0 {
+	if req.Quantity.Value.Sign() <= 0 {
 		http.Error(w, "quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if btoi(req.Nested)+btoi(req.Shipments != nil)+btoi(req.Partial)+btoi(req.Sourcing) > 1 {
+		http.Error(w, "nested, shipments, partial and sourcing cannot be combined", http.StatusBadRequest)
+		return
 	}
 
-	packs, err := h.srv.Compute(r.Context(), req.Sku, req.Quantity)
+	objective, err := algorithms.ParseObjective(req.Objective)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	_ = json.NewEncoder(w).Encode(packs)
+	opts := allocation.Options{
+		Objective:   objective,
+		Exclude:     req.Exclude,
+		Require:     req.Require,
+		MaxOverfill: req.MaxOverfill,
+		Algorithm:   req.Algorithm,
+		// Note: the alternatives are part of the same request, they draw on the budget of the allocation.
+		Meter: h.srv.Meter(r.Context()),
+	}
+
+	var resp AllocateResponse
+	switch {
+	case req.Shipments != nil:
+		res, err := h.srv.ComputeShipments(r.Context(), req.Sku, req.Quantity, opts, *req.Shipments)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+		resp.Result, resp.ShipmentLimits, resp.Shipments, resp.FewestShipments = res.Result, &res.Limits, res.Shipments, res.Fewest
+	case req.Sourcing:
+		res, err := h.srv.ComputeSourced(r.Context(), req.Sku, req.Quantity, opts)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+		resp.Result, resp.Locations, resp.ShippingCost, resp.Cost = res.Result, res.Locations, &res.ShippingCost, &res.Cost
+	case req.Partial:
+		res, err := h.srv.ComputePartial(r.Context(), req.Sku, req.Quantity, opts)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+		resp.Result, resp.Shortfall, resp.Backorder = res.Result, &res.Shortfall, res.Backorder
+	case req.Nested:
+		res, err := h.srv.ComputeNested(r.Context(), req.Sku, req.Quantity, opts)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+		resp.Result, resp.Levels, resp.Tree = res.Result, res.Levels, res.Tree
+	default:
+		res, err := h.srv.Compute(r.Context(), req.Sku, req.Quantity, opts)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+		resp.Result = res
+	}
+	resp.Objective = objective
+
+	// Note: alternatives are allocated from stock too, there are none when it falls short.
+	if req.Alternatives && resp.Backorder == nil {
+		resp.Alternatives, err = h.srv.Alternatives(r.Context(), req.Sku, req.Quantity, opts)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
 	return
 }
+
+type AllocateBatchRequest struct {
+	Sku         string               `json:"sku"`
+	Quantities  []pack.Amount        `json:"quantities"`
+	Objective   string               `json:"objective"`
+	Exclude     []pack.ID            `json:"exclude"`
+	Require     []pack.ID            `json:"require"`
+	MaxOverfill algorithms.Tolerance `json:"max_overfill"`
+	Algorithm   string               `json:"algorithm"`
+}
+
+// AllocateBatchResult is the outcome for one quantity, Error is set instead of allocations when it failed.
+type AllocateBatchResult struct {
+	Quantity    pack.Amount      `json:"quantity"`
+	Algorithm   string           `json:"algorithm,omitempty"`
+	TotalCost   int64            `json:"total_cost"`
+	Allocations pack.Allocations `json:"allocations,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Status      int              `json:"status"`
+}
+
+type AllocateBatchResponse struct {
+	Objective algorithms.Objective  `json:"objective"`
+	Results   []AllocateBatchResult `json:"results"`
+}
+
+func (h *AllocationHandler) HandleAllocateBatch(w http.ResponseWriter, r *http.Request) {
+	var req AllocateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Sku == "" {
+		http.Error(w, "sku is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Quantities) == 0 {
+		http.Error(w, "quantities are required", http.StatusBadRequest)
+		return
+	}
+	for _, q := range req.Quantities {
+		if q.Value.Sign() <= 0 {
+			http.Error(w, "quantities must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+
+	objective, err := algorithms.ParseObjective(req.Objective)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.srv.ComputeBatch(r.Context(), req.Sku, req.Quantities, allocation.Options{
+		Objective:   objective,
+		Exclude:     req.Exclude,
+		Require:     req.Require,
+		MaxOverfill: req.MaxOverfill,
+		Algorithm:   req.Algorithm,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), allocationStatus(err))
+		return
+	}
+
+	resp := AllocateBatchResponse{
+		Objective: objective,
+		Results:   make([]AllocateBatchResult, len(results)),
+	}
+	for i, res := range results {
+		out := AllocateBatchResult{
+			Quantity: req.Quantities[i],
+			Status:   http.StatusOK,
+		}
+		if res.Err != nil {
+			out.Error = res.Err.Error()
+			out.Status = allocationStatus(res.Err)
+		} else {
+			out.Algorithm = res.Algorithm
+			out.TotalCost = res.Allocations.TotalCost()
+			out.Allocations = res.Allocations
+		}
+		resp.Results[i] = out
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type AllocateOrderRequest struct {
+	Lines       []pack.OrderLine     `json:"lines"`
+	Objective   string               `json:"objective"`
+	MaxOverfill algorithms.Tolerance `json:"max_overfill"`
+	Algorithm   string               `json:"algorithm"`
+	// Bundles allocates the lines together, taking bundles holding several SKUs of the order as well.
+	Bundles bool `json:"bundles"`
+}
+
+// AllocateOrderLine is the outcome for one line, Error is set instead of the allocation when it failed.
+type AllocateOrderLine struct {
+	pack.OrderLine
+	*allocation.Result
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status"`
+}
+
+type AllocateOrderResponse struct {
+	Objective algorithms.Objective   `json:"objective"`
+	Lines     []AllocateOrderLine    `json:"lines"`
+	Totals    allocation.OrderTotals `json:"totals"`
+}
+
+type AllocateBundledResponse struct {
+	Objective algorithms.Objective `json:"objective"`
+	allocation.Bundled
+}
+
+// HandleAllocateOrder allocates every line of an order. The response lists all lines either way, its status
+// is the one of the failed lines when there are any. With bundles the lines succeed or fail together.
+func (h *AllocationHandler) HandleAllocateOrder(w http.ResponseWriter, r *http.Request) {
+	var req AllocateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := pack.NewOrder(req.Lines)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	objective, err := algorithms.ParseObjective(req.Objective)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := allocation.Options{
+		Objective:   objective,
+		MaxOverfill: req.MaxOverfill,
+		Algorithm:   req.Algorithm,
+	}
+
+	if req.Bundles {
+		res, err := h.srv.ComputeBundled(r.Context(), order, opts)
+		if err != nil {
+			http.Error(w, err.Error(), allocationStatus(err))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(AllocateBundledResponse{Objective: objective, Bundled: res})
+		return
+	}
+
+	res, err := h.srv.ComputeOrder(r.Context(), order, opts)
+	if err != nil {
+		http.Error(w, err.Error(), allocationStatus(err))
+		return
+	}
+
+	resp := AllocateOrderResponse{
+		Objective: objective,
+		Lines:     make([]AllocateOrderLine, len(res.Lines)),
+		Totals:    res.Totals,
+	}
+	for i, line := range res.Lines {
+		out := AllocateOrderLine{
+			OrderLine: line.Line,
+			Status:    http.StatusOK,
+		}
+		if line.Err != nil {
+			out.Error = line.Err.Error()
+			out.Status = allocationStatus(line.Err)
+		} else {
+			out.Result = &line.Result
+		}
+		resp.Lines[i] = out
+	}
+
+	if err := res.Err(); err != nil {
+		w.WriteHeader(allocationStatus(err))
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// CurrentPack is a number of packs of one size already allocated.
+type CurrentPack struct {
+	Size     pack.ID       `json:"size"`
+	Quantity pack.Quantity `json:"quantity"`
+}
+
+type ReallocateRequest struct {
+	Sku      string      `json:"sku"`
+	Quantity pack.Amount `json:"quantity"`
+	// Current is the allocation already being picked.
+	Current     []CurrentPack        `json:"current"`
+	Objective   string               `json:"objective"`
+	Exclude     []pack.ID            `json:"exclude"`
+	Require     []pack.ID            `json:"require"`
+	MaxOverfill algorithms.Tolerance `json:"max_overfill"`
+	Algorithm   string               `json:"algorithm"`
+}
+
+type ReallocateResponse struct {
+	Objective algorithms.Objective `json:"objective"`
+	allocation.Reallocation
+}
+
+func (h *AllocationHandler) HandleReallocate(w http.ResponseWriter, r *http.Request) {
+	var req ReallocateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Sku == "" {
+		http.Error(w, "sku is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Quantity.Value.Sign() <= 0 {
+		http.Error(w, "quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	current := make(pack.Allocations, 0, len(req.Current))
+	for _, c := range req.Current {
+		if c.Quantity < 0 {
+			http.Error(w, "current quantities must not be negative", http.StatusBadRequest)
+			return
+		}
+		current = append(current, pack.Allocation{Size: pack.Size{ID: c.Size}, Quantity: c.Quantity})
+	}
+
+	objective, err := algorithms.ParseObjective(req.Objective)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.srv.Reallocate(r.Context(), req.Sku, current, req.Quantity, allocation.Options{
+		Objective:   objective,
+		Exclude:     req.Exclude,
+		Require:     req.Require,
+		MaxOverfill: req.MaxOverfill,
+		Algorithm:   req.Algorithm,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), allocationStatus(err))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(ReallocateResponse{
+		Objective:    objective,
+		Reallocation: res,
+	})
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// allocationStatus translates allocation errors into HTTP status codes.
+func allocationStatus(err error) int {
+	var budgetErr *algorithms.BudgetError
+	switch {
+	case errors.Is(err, algorithms.ErrInsufficientStock),
+		errors.Is(err, algorithms.ErrInfeasible),
+		errors.Is(err, allocation.ErrUnsatisfiable),
+		errors.Is(err, allocation.ErrNoLocations),
+		errors.Is(err, algorithms.ErrOutOfTolerance),
+		errors.Is(err, algorithms.ErrDemandTooLarge),
+		errors.Is(err, algorithms.ErrOverLimit):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, pack.ErrUnknownSize),
+		errors.Is(err, algorithms.ErrUnknownAlgorithm),
+		errors.Is(err, pack.ErrIncompatibleUnits),
+		errors.Is(err, pack.ErrInvalidAmount):
+		return http.StatusBadRequest
+	case errors.As(err, &budgetErr) && budgetErr.Resource == algorithms.Cells:
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, pack.ErrUnknownInventory):
+		return http.StatusNotFound
+	case errors.Is(err, algorithms.ErrInvalidAllocation):
+		return http.StatusInternalServerError
+	case errors.Is(err, errors.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, algorithms.ErrBudgetExceeded),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/IAmRadek/packing/internal/app/inventory"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+	"github.com/IAmRadek/packing/internal/templates"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+)
+
+type BundleHandler struct {
+	invSrv *inventory.Service
+	render *templates.Templates
+	dec    *schema.Decoder
+}
+
+func NewBundleHandler(
+	invSrv *inventory.Service,
+	render *templates.Templates,
+	dec *schema.Decoder,
+) *BundleHandler {
+	return &BundleHandler{
+		invSrv: invSrv,
+		render: render,
+		dec:    dec,
+	}
+}
+
+type BundleRequest struct {
+	ID    string `schema:"id"`
+	Label string `schema:"label"`
+	Price int64  `schema:"price"`
+	// Contents is parsed by pack.ParseBundleContents.
+	Contents string `schema:"contents"`
+}
+
+type BundlesResponse struct {
+	Bundles []pack.Bundle
+	// SKUs lists the inventories bundles can hold.
+	SKUs []string
+	// Form is the bundle as it was entered, to fill the form again when it is rejected.
+	Form   BundleRequest
+	Errors []string
+}
+
+// HandleBundles lists the bundles and saves the submitted one, replacing a bundle of the same ID.
+func (h *BundleHandler) HandleBundles(w http.ResponseWriter, r *http.Request) {
+	var resp BundlesResponse
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.dec.Decode(&resp.Form, r.PostForm); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contents, err := pack.ParseBundleContents(resp.Form.Contents)
+		if err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+
+		if len(resp.Errors) == 0 {
+			b, err := pack.NewBundle(pack.ID(strings.TrimSpace(resp.Form.ID)), strings.TrimSpace(resp.Form.Label), resp.Form.Price, contents)
+			if err == nil {
+				err = h.invSrv.SaveBundle(r.Context(), b)
+			}
+			if err != nil {
+				resp.Errors = append(resp.Errors, strings.Split(err.Error(), "\n")...)
+			}
+		}
+
+		if len(resp.Errors) == 0 {
+			http.Redirect(w, r, "/bundles", http.StatusFound)
+			return
+		}
+	}
+
+	bundles, err := h.invSrv.Bundles(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp.Bundles = bundles
+
+	invs, err := h.invSrv.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, inv := range invs {
+		resp.SKUs = append(resp.SKUs, inv.SKU())
+	}
+
+	h.render.Render(w, r, "bundles", resp)
+}
+
+func (h *BundleHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if vars["id"] == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.invSrv.DeleteBundle(r.Context(), pack.ID(vars["id"])); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/bundles", http.StatusFound)
+}
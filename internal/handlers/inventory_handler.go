@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/IAmRadek/packing/internal/algorithms"
 	"github.com/IAmRadek/packing/internal/app/allocation"
 	"github.com/IAmRadek/packing/internal/app/inventory"
 	"github.com/IAmRadek/packing/internal/domain/pack"
@@ -47,18 +50,29 @@ func (h *InventoryHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 }
 
 type InventoryCreateRequest struct {
-	Name       string   `schema:"name"`
-	Labels     []string `schema:"pack_name[]"`
-	Quantities []int64  `schema:"pack_quantity[]"`
+	Name   string   `schema:"name"`
+	Labels []string `schema:"pack_name[]"`
+	// Quantities are the capacities of the packs in Unit, parsed by pack.ParseDecimal.
+	Quantities []string `schema:"pack_quantity[]"`
+	Stocks     []int64  `schema:"pack_stock[]"`
+	Prices     []int64  `schema:"pack_price[]"`
+	Minimums   []int64  `schema:"pack_min_quantity[]"`
+	Multiples  []int64  `schema:"pack_multiple[]"`
+	TrackStock bool     `schema:"track_stock"`
+	Unit       string   `schema:"unit"`
+	Precision  int      `schema:"precision"`
 }
 
 type InventoryCreateResponse struct {
 	Error string
 	Name  string
+	Units []pack.Unit
 }
 
 func (h *InventoryHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
-	resp := InventoryCreateResponse{}
+	resp := InventoryCreateResponse{
+		Units: pack.Units,
+	}
 
 	if r.Method == http.MethodPost {
 		if err := r.ParseForm(); err != nil {
@@ -85,14 +99,54 @@ func (h *InventoryHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		sizes, err := pack.NewSizes(req.Quantities, req.Labels)
+		cons, err := constraints(req.Minimums, req.Multiples)
+		if err != nil {
+			resp.Error = err.Error()
+			h.render.Render(w, r, "inventory_create", resp)
+			return
+		}
+
+		measure, err := pack.NewMeasure(req.Unit, req.Precision)
+		if err != nil {
+			resp.Error = err.Error()
+			h.render.Render(w, r, "inventory_create", resp)
+			return
+		}
+
+		quantities, err := capacities(measure, req.Quantities)
+		if err != nil {
+			resp.Error = err.Error()
+			h.render.Render(w, r, "inventory_create", resp)
+			return
+		}
+
+		sizes, err := pack.NewSizes(quantities, req.Labels, cons...)
+		if err != nil {
+			resp.Error = err.Error()
+			h.render.Render(w, r, "inventory_create", resp)
+			return
+		}
+
+		sizes, err = sizes.WithStock(req.Stocks)
+		if err != nil {
+			resp.Error = err.Error()
+			h.render.Render(w, r, "inventory_create", resp)
+			return
+		}
+
+		sizes, err = sizes.WithPrices(req.Prices)
 		if err != nil {
 			resp.Error = err.Error()
 			h.render.Render(w, r, "inventory_create", resp)
 			return
 		}
 
-		if err := h.invSrv.Create(r.Context(), sanitize(req.Name), sizes); err != nil {
+		settings := inventory.Settings{
+			TrackStock: req.TrackStock,
+			Measure:    measure,
+		}
+
+		if err := h.invSrv.Create(r.Context(), sanitize(req.Name), sizes, settings); err != nil {
 			resp.Error = err.Error()
 			h.render.Render(w, r, "inventory_create", resp)
 			return
@@ -103,13 +157,60 @@ func (h *InventoryHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 }
 
 type InventoryGetRequest struct {
-	Demand int64 `schema:"demand"`
+	// Demand is parsed by pack.ParseAmount, it is in the measure of the inventory unless it names a unit.
+	Demand    string `schema:"demand"`
+	Objective string `schema:"objective"`
+	Exclude   string `schema:"exclude"`
+	Require   string `schema:"require"`
+	// MaxOverfill is parsed by algorithms.ParseTolerance.
+	MaxOverfill string `schema:"max_overfill"`
+	Compare     bool   `schema:"compare"`
+	// Algorithm overrides the allocator of the inventory, empty keeps it.
+	Algorithm string `schema:"algorithm"`
+	// Shipments splits the allocation into shipments of at most MaxWeight grams and MaxVolume cubic centimetres.
+	Shipments bool  `schema:"shipments"`
+	MaxWeight int64 `schema:"max_weight"`
+	MaxVolume int64 `schema:"max_volume"`
+	// Partial ships what the stock covers and backorders the rest.
+	Partial bool `schema:"partial"`
+	// Sourcing takes the packs from the stock of the locations for the lowest cost.
+	Sourcing bool `schema:"sourcing"`
 }
 
 type InventoryGetResponse struct {
-	Inventory   *pack.Inventory
-	Demand      int64
-	Allocations pack.Allocations
+	Inventory *pack.Inventory
+	Units     []pack.Unit
+	// Algorithms lists the allocators to pick from, DefaultAlgorithm is used when none is picked.
+	Algorithms       []string
+	DefaultAlgorithm string
+	// Algorithm is the override of the request.
+	Algorithm   string
+	Demand      pack.Amount
+	Objective   algorithms.Objective
+	Exclude     string
+	Require     string
+	MaxOverfill algorithms.Tolerance
+	Result      allocation.Result
+	// Levels and Tree nest the result in the packaging hierarchy of the inventory, if it has one.
+	Levels []allocation.LevelResult
+	Tree   []pack.Node
+	// Shipments lists the split of the result when SplitShipments is set, within ShipmentLimits.
+	SplitShipments  bool
+	ShipmentLimits  algorithms.ShipmentLimits
+	Shipments       []allocation.Shipment
+	FewestShipments bool
+	// Shortfall and Backorder are set when Partial is and the stock falls short of the demand.
+	Partial   bool
+	Shortfall pack.Decimal
+	Backorder *allocation.Result
+	// Locations stock the inventory, Sourced splits the result between them when Sourcing is set.
+	Locations    pack.Locations
+	Sourcing     bool
+	Sourced      []allocation.LocationResult
+	ShippingCost int64
+	Cost         int64
+	Compare      bool
+	Alternatives []allocation.Alternative
 }
 
 func (h *InventoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
@@ -119,7 +220,12 @@ func (h *InventoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := InventoryGetResponse{}
+	resp := InventoryGetResponse{
+		Units:            pack.Units,
+		ShipmentLimits:   h.allocSrv.ShipmentLimits(),
+		Algorithms:       h.allocSrv.Algorithms(),
+		DefaultAlgorithm: h.allocSrv.DefaultAlgorithm(),
+	}
 
 	inv, err := h.invSrv.Get(r.Context(), vars["sku"])
 	if err != nil {
@@ -129,6 +235,12 @@ func (h *InventoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 
 	resp.Inventory = inv
 
+	resp.Locations, err = h.invSrv.Locations(r.Context(), inv.SKU())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	if r.Method == http.MethodPost {
 		if err := r.ParseForm(); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -142,25 +254,162 @@ func (h *InventoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		packs, err := h.allocSrv.Compute(r.Context(), inv.SKU(), req.Demand)
+		demand, err := pack.ParseAmount(req.Demand)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if demand.Value.Sign() <= 0 {
+			http.Error(w, "demand must be positive", http.StatusBadRequest)
 			return
 		}
 
-		resp.Demand = req.Demand
-		resp.Allocations = packs
+		objective, err := algorithms.ParseObjective(req.Objective)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tolerance, err := algorithms.ParseTolerance(req.MaxOverfill)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		opts := allocation.Options{
+			Objective:   objective,
+			Exclude:     splitIDs(req.Exclude),
+			Require:     splitIDs(req.Require),
+			MaxOverfill: tolerance,
+			Algorithm:   req.Algorithm,
+		}
+
+		if btoi(req.Shipments)+btoi(req.Partial)+btoi(req.Sourcing) > 1 {
+			http.Error(w, "shipments, partial and sourcing cannot be combined", http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case req.Sourcing:
+			sourced, err := h.allocSrv.ComputeSourced(r.Context(), inv.SKU(), demand, opts)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+			resp.Result, resp.Sourced = sourced.Result, sourced.Locations
+			resp.ShippingCost, resp.Cost = sourced.ShippingCost, sourced.Cost
+		case req.Partial:
+			partial, err := h.allocSrv.ComputePartial(r.Context(), inv.SKU(), demand, opts)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+			resp.Result, resp.Shortfall, resp.Backorder = partial.Result, partial.Shortfall, partial.Backorder
+		case req.Shipments:
+			limits := algorithms.ShipmentLimits{MaxWeight: req.MaxWeight, MaxVolume: req.MaxVolume}
+			shipped, err := h.allocSrv.ComputeShipments(r.Context(), inv.SKU(), demand, opts, limits)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+			resp.Result, resp.Shipments, resp.FewestShipments = shipped.Result, shipped.Shipments, shipped.Fewest
+			resp.SplitShipments, resp.ShipmentLimits = true, shipped.Limits
+		case len(inv.Hierarchy()) > 0:
+			nested, err := h.allocSrv.ComputeNested(r.Context(), inv.SKU(), demand, opts)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+			resp.Result, resp.Levels, resp.Tree = nested.Result, nested.Levels, nested.Tree
+		default:
+			res, err := h.allocSrv.Compute(r.Context(), inv.SKU(), demand, opts)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+			resp.Result = res
+		}
+
+		if req.Compare && resp.Backorder == nil {
+			resp.Alternatives, err = h.allocSrv.Alternatives(r.Context(), inv.SKU(), demand, opts)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+		}
+
+		resp.Demand = demand
+		resp.Objective = objective
+		resp.Exclude = req.Exclude
+		resp.Require = req.Require
+		resp.MaxOverfill = tolerance
+		resp.Compare = req.Compare
+		resp.Partial = req.Partial
+		resp.Sourcing = req.Sourcing
+		resp.Algorithm = req.Algorithm
 	}
 
 	h.render.Render(w, r, "inventory_get", resp)
 }
 
+type InventoryFeasibilityResponse struct {
+	Inventory   *pack.Inventory
+	Feasibility algorithms.Feasibility
+}
+
+func (h *InventoryHandler) HandleFeasibility(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if vars["sku"] == "" {
+		http.Error(w, "sku is required", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invSrv.Get(r.Context(), vars["sku"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := h.allocSrv.Feasibility(r.Context(), inv.SKU())
+	if err != nil {
+		http.Error(w, err.Error(), allocationStatus(err))
+		return
+	}
+
+	h.render.Render(w, r, "inventory_feasibility", InventoryFeasibilityResponse{
+		Inventory:   inv,
+		Feasibility: report,
+	})
+}
+
 type InventoryUpdateRequest struct {
-	SKU           string   `schema:"sku"`
-	Labels        []string `schema:"label[]"`
-	Capacities    []int64  `schema:"capacity[]"`
+	SKU    string   `schema:"sku"`
+	Labels []string `schema:"label[]"`
+	// Capacities and NewCapacities are in Unit, parsed by pack.ParseDecimal.
+	Capacities    []string `schema:"capacity[]"`
+	Stocks        []int64  `schema:"stock[]"`
+	Prices        []int64  `schema:"price[]"`
+	Minimums      []int64  `schema:"min_quantity[]"`
+	Multiples     []int64  `schema:"multiple[]"`
 	NewLabels     []string `schema:"new_label[]"`
-	NewCapacities []int64  `schema:"new_capacity[]"`
+	NewCapacities []string `schema:"new_capacity[]"`
+	NewStocks     []int64  `schema:"new_stock[]"`
+	NewPrices     []int64  `schema:"new_price[]"`
+	NewMinimums   []int64  `schema:"new_min_quantity[]"`
+	NewMultiples  []int64  `schema:"new_multiple[]"`
+	// Weights are in grams, Dimensions are parsed by pack.ParseDimensions.
+	Weights       []int64  `schema:"weight[]"`
+	Dimensions    []string `schema:"dimensions[]"`
+	NewWeights    []int64  `schema:"new_weight[]"`
+	NewDimensions []string `schema:"new_dimensions[]"`
+	TrackStock    bool     `schema:"track_stock"`
+	TieBreak      string   `schema:"tie_break"`
+	Priority      string   `schema:"priority"`
+	Algorithm     string   `schema:"algorithm"`
+	Unit          string   `schema:"unit"`
+	Precision     int      `schema:"precision"`
+	// Packaging is parsed by pack.ParseHierarchy.
+	Packaging string `schema:"packaging"`
 }
 
 func (h *InventoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
@@ -189,7 +438,43 @@ func (h *InventoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		sizes, err := pack.NewSizes(req.Capacities, req.Labels)
+		cons, err := constraints(req.Minimums, req.Multiples)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		measure, err := pack.NewMeasure(req.Unit, req.Precision)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		caps, err := capacities(measure, req.Capacities)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sizes, err := pack.NewSizes(caps, req.Labels, cons...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sizes, err = sizes.WithStock(req.Stocks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sizes, err = sizes.WithPrices(req.Prices)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sizes, err = withPhysical(sizes, req.Weights, req.Dimensions)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -197,8 +482,37 @@ func (h *InventoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 
 		var newSizes pack.Sizes
 		if len(req.NewLabels) > 0 {
-			var err error
-			newSizes, err = pack.NewSizes(req.NewCapacities, req.NewLabels)
+			newCons, err := constraints(req.NewMinimums, req.NewMultiples)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			newCaps, err := capacities(measure, req.NewCapacities)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			newSizes, err = pack.NewSizes(newCaps, req.NewLabels, newCons...)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			newSizes, err = newSizes.WithStock(req.NewStocks)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			newSizes, err = newSizes.WithPrices(req.NewPrices)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			newSizes, err = withPhysical(newSizes, req.NewWeights, req.NewDimensions)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
@@ -211,7 +525,32 @@ func (h *InventoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		if err := h.invSrv.Update(r.Context(), vars["sku"], allSizes); err != nil {
+		tieBreak, err := pack.NewTieBreak(req.TieBreak, splitIDs(req.Priority))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hierarchy, err := pack.ParseHierarchy(req.Packaging)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Algorithm != "" && !slices.Contains(h.allocSrv.Algorithms(), req.Algorithm) {
+			http.Error(w, fmt.Sprintf("%v: %q", algorithms.ErrUnknownAlgorithm, req.Algorithm), http.StatusBadRequest)
+			return
+		}
+
+		settings := inventory.Settings{
+			TrackStock: req.TrackStock,
+			TieBreak:   tieBreak,
+			Algorithm:  req.Algorithm,
+			Measure:    measure,
+			Hierarchy:  hierarchy,
+		}
+
+		if err := h.invSrv.Update(r.Context(), vars["sku"], allSizes, settings); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	}
@@ -219,6 +558,72 @@ func (h *InventoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/inventory/"+inv.SKU(), http.StatusFound)
 }
 
+type InventoryLocationsRequest struct {
+	// IDs and ShippingCosts describe one location each, Stocks list the stock of every size per location
+	// in the order of the sizes. Locations left without an ID are removed.
+	IDs           []string        `schema:"location[]"`
+	ShippingCosts []int64         `schema:"shipping_cost[]"`
+	Stocks        []pack.Quantity `schema:"location_stock[]"`
+}
+
+// HandleLocations replaces the locations stocking the inventory.
+func (h *InventoryHandler) HandleLocations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if vars["sku"] == "" {
+		http.Error(w, "sku is required", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invSrv.Get(r.Context(), vars["sku"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req InventoryLocationsRequest
+
+	if err := h.dec.Decode(&req, r.PostForm); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sizes := inv.AvailableSizes()
+	if len(req.ShippingCosts) != len(req.IDs) || len(req.Stocks) != len(req.IDs)*len(sizes) {
+		http.Error(w, "every location needs a shipping cost and the stock of every size", http.StatusBadRequest)
+		return
+	}
+
+	var locations pack.Locations
+	for i, id := range req.IDs {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		loc := pack.Location{
+			ID:           id,
+			ShippingCost: req.ShippingCosts[i],
+			Stock:        make(map[pack.ID]pack.Quantity, len(sizes)),
+		}
+		for j, size := range sizes {
+			if q := req.Stocks[i*len(sizes)+j]; q != 0 {
+				loc.Stock[size.ID] = q
+			}
+		}
+		locations = append(locations, loc)
+	}
+
+	if err := h.invSrv.SetLocations(r.Context(), inv.SKU(), locations); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/inventory/"+inv.SKU(), http.StatusFound)
+}
+
 func (h *InventoryHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	if vars["sku"] == "" {
@@ -240,6 +645,62 @@ func (h *InventoryHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/inventory", http.StatusFound)
 }
 
+// constraints pairs up minimum quantities and multiples submitted per size.
+func constraints(minimums, multiples []int64) ([]pack.Constraint, error) {
+	if len(minimums) != len(multiples) {
+		return nil, fmt.Errorf("minimum quantities and multiples must have the same length")
+	}
+
+	out := make([]pack.Constraint, len(minimums))
+	for i := range minimums {
+		out[i] = pack.Constraint{
+			MinQuantity: pack.Quantity(minimums[i]),
+			Multiple:    pack.Quantity(multiples[i]),
+		}
+	}
+	return out, nil
+}
+
+// withPhysical sets the weights and dimensions submitted per size.
+func withPhysical(sizes pack.Sizes, weights []int64, dimensions []string) (pack.Sizes, error) {
+	dims := make([]pack.Dimensions, len(dimensions))
+	for i, d := range dimensions {
+		var err error
+		if dims[i], err = pack.ParseDimensions(d); err != nil {
+			return nil, err
+		}
+	}
+	return sizes.WithPhysical(weights, dims)
+}
+
+// capacities scales capacities given in the unit of a measure to whole steps of it.
+func capacities(m pack.Measure, values []string) ([]int64, error) {
+	out := make([]int64, len(values))
+	for i, v := range values {
+		d, err := pack.ParseDecimal(v)
+		if err != nil {
+			return nil, fmt.Errorf("capacity: %w", err)
+		}
+		c, err := m.ScaleExact(pack.Amount{Value: d})
+		if err != nil {
+			return nil, fmt.Errorf("capacity: %w", err)
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+// splitIDs parses a comma separated list of size IDs.
+func splitIDs(input string) []pack.ID {
+	var out []pack.ID
+	for _, id := range strings.Split(input, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			out = append(out, pack.ID(id))
+		}
+	}
+	return out
+}
+
 func sanitize(input string) string {
 	reg := regexp.MustCompile(`[^a-zA-Z0-9\s]`)
 	sanitized := reg.ReplaceAllString(input, "")
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/IAmRadek/packing/internal/algorithms"
+	"github.com/IAmRadek/packing/internal/app/allocation"
+	"github.com/IAmRadek/packing/internal/app/inventory"
+	"github.com/IAmRadek/packing/internal/domain/pack"
+	"github.com/IAmRadek/packing/internal/templates"
+	"github.com/gorilla/schema"
+)
+
+type OrderHandler struct {
+	invSrv   *inventory.Service
+	allocSrv *allocation.Service
+	render   *templates.Templates
+	dec      *schema.Decoder
+}
+
+func NewOrderHandler(
+	invSrv *inventory.Service,
+	allocSrv *allocation.Service,
+	render *templates.Templates,
+	dec *schema.Decoder,
+) *OrderHandler {
+	return &OrderHandler{
+		invSrv:   invSrv,
+		allocSrv: allocSrv,
+		render:   render,
+		dec:      dec,
+	}
+}
+
+type OrderRequest struct {
+	// SKUs and Quantities are the lines of the order matched by index, rows leaving both empty are skipped.
+	// Quantities are parsed by pack.ParseAmount.
+	SKUs       []string `schema:"sku[]"`
+	Quantities []string `schema:"quantity[]"`
+	Objective  string   `schema:"objective"`
+	// MaxOverfill is parsed by algorithms.ParseTolerance.
+	MaxOverfill string `schema:"max_overfill"`
+	Algorithm   string `schema:"algorithm"`
+	Bundles     bool   `schema:"bundles"`
+}
+
+// OrderFormLine is a line as it was entered, to fill the form again.
+type OrderFormLine struct {
+	SKU      string
+	Quantity string
+}
+
+type OrderResponse struct {
+	// SKUs lists the inventories lines can pick from.
+	SKUs        []string
+	Algorithms  []string
+	Lines       []OrderFormLine
+	Objective   algorithms.Objective
+	MaxOverfill algorithms.Tolerance
+	Algorithm   string
+	Bundles     bool
+	// Errors lists the problems of the form, Result or Bundled is only set when there are none.
+	Errors  []string
+	Result  *allocation.OrderResult
+	Bundled *allocation.Bundled
+}
+
+// HandleOrder shows the order form and allocates the submitted order, listing the failed lines together.
+// With bundles the order is allocated with the bundles holding its SKUs as well.
+func (h *OrderHandler) HandleOrder(w http.ResponseWriter, r *http.Request) {
+	invs, err := h.invSrv.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := OrderResponse{
+		Algorithms: h.allocSrv.Algorithms(),
+		Lines:      []OrderFormLine{{}},
+	}
+	for _, inv := range invs {
+		resp.SKUs = append(resp.SKUs, inv.SKU())
+	}
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req OrderRequest
+
+		if err := h.dec.Decode(&req, r.PostForm); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(req.SKUs) != len(req.Quantities) {
+			http.Error(w, "skus and quantities must have the same length", http.StatusBadRequest)
+			return
+		}
+
+		resp.Lines = resp.Lines[:0]
+		resp.Algorithm = req.Algorithm
+		resp.Bundles = req.Bundles
+
+		var lines []pack.OrderLine
+		for i, sku := range req.SKUs {
+			sku, quantity := strings.TrimSpace(sku), strings.TrimSpace(req.Quantities[i])
+			if sku == "" && quantity == "" {
+				continue
+			}
+			resp.Lines = append(resp.Lines, OrderFormLine{SKU: sku, Quantity: quantity})
+
+			amount, err := pack.ParseAmount(quantity)
+			if err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("line %d: %v", len(resp.Lines), err))
+			}
+			lines = append(lines, pack.OrderLine{SKU: sku, Quantity: amount})
+		}
+		if len(resp.Lines) == 0 {
+			resp.Lines = append(resp.Lines, OrderFormLine{})
+		}
+
+		order, err := pack.NewOrder(lines)
+		if err != nil {
+			resp.Errors = append(resp.Errors, strings.Split(err.Error(), "\n")...)
+		}
+
+		resp.Objective, err = algorithms.ParseObjective(req.Objective)
+		if err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+
+		resp.MaxOverfill, err = algorithms.ParseTolerance(req.MaxOverfill)
+		if err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+
+		opts := allocation.Options{
+			Objective:   resp.Objective,
+			MaxOverfill: resp.MaxOverfill,
+			Algorithm:   req.Algorithm,
+		}
+
+		switch {
+		case len(resp.Errors) > 0:
+		case req.Bundles:
+			// Note: bundled lines are allocated together, so a failing line fails the order as a whole.
+			res, err := h.allocSrv.ComputeBundled(r.Context(), order, opts)
+			if err != nil {
+				resp.Errors = append(resp.Errors, strings.Split(err.Error(), "\n")...)
+				break
+			}
+			resp.Bundled = &res
+		default:
+			res, err := h.allocSrv.ComputeOrder(r.Context(), order, opts)
+			if err != nil {
+				http.Error(w, err.Error(), allocationStatus(err))
+				return
+			}
+			resp.Result = &res
+		}
+	}
+
+	h.render.Render(w, r, "order", resp)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/IAmRadek/packing/internal/app/allocation"
+	"github.com/IAmRadek/packing/internal/templates"
+)
+
+type ShadowHandler struct {
+	srv    *allocation.Service
+	render *templates.Templates
+}
+
+func NewShadowHandler(srv *allocation.Service, render *templates.Templates) *ShadowHandler {
+	return &ShadowHandler{
+		srv:    srv,
+		render: render,
+	}
+}
+
+// HandleShadow lists the counters and recent mismatches of the shadow allocator.
+func (h *ShadowHandler) HandleShadow(w http.ResponseWriter, r *http.Request) {
+	h.render.Render(w, r, "shadow", h.srv.ShadowReport())
+}
@@ -1,22 +1,28 @@
 package infra
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/IAmRadek/packing/internal/domain/pack"
 )
 
 type MemoryRepo struct {
-	rw *sync.RWMutex
-	m  map[string]*pack.Inventory
+	rw        *sync.RWMutex
+	m         map[string]*pack.Inventory
+	locations map[string]pack.Locations
+	bundles   map[pack.ID]pack.Bundle
 }
 
 func NewMemoryRepo() *MemoryRepo {
 	return &MemoryRepo{
-		rw: &sync.RWMutex{},
-		m:  make(map[string]*pack.Inventory),
+		rw:        &sync.RWMutex{},
+		m:         make(map[string]*pack.Inventory),
+		locations: make(map[string]pack.Locations),
+		bundles:   make(map[pack.ID]pack.Bundle),
 	}
 }
 
@@ -36,6 +42,7 @@ func (m *MemoryRepo) DeleteInventory(ctx context.Context, sku string) error {
 	defer m.rw.Unlock()
 
 	delete(m.m, sku)
+	delete(m.locations, sku)
 	return nil
 }
 
@@ -53,7 +60,63 @@ func (m *MemoryRepo) GetInventory(ctx context.Context, sku string) (*pack.Invent
 
 	inv, ok := m.m[sku]
 	if !ok {
-		return nil, fmt.Errorf("inventory not found for sku: %s", sku)
+		return nil, fmt.Errorf("%w: %s", pack.ErrUnknownInventory, sku)
 	}
 	return inv, nil
 }
+
+// ListLocations returns the locations stocking the inventory of sku, none when it is only stocked centrally.
+func (m *MemoryRepo) ListLocations(ctx context.Context, sku string) (pack.Locations, error) {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	if _, ok := m.m[sku]; !ok {
+		return nil, fmt.Errorf("%w: %s", pack.ErrUnknownInventory, sku)
+	}
+	return slices.Clone(m.locations[sku]), nil
+}
+
+// SaveLocations replaces the locations stocking the inventory of sku.
+func (m *MemoryRepo) SaveLocations(ctx context.Context, sku string, locations pack.Locations) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	if _, ok := m.m[sku]; !ok {
+		return fmt.Errorf("%w: %s", pack.ErrUnknownInventory, sku)
+	}
+	m.locations[sku] = slices.Clone(locations)
+	return nil
+}
+
+// ListBundles returns every bundle ordered by ID.
+func (m *MemoryRepo) ListBundles(ctx context.Context) ([]pack.Bundle, error) {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	out := make([]pack.Bundle, 0, len(m.bundles))
+	for _, b := range m.bundles {
+		out = append(out, b)
+	}
+	slices.SortFunc(out, func(a, b pack.Bundle) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
+
+// SaveBundle adds the bundle or replaces the one of the same ID.
+func (m *MemoryRepo) SaveBundle(ctx context.Context, b pack.Bundle) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	b.Contents = slices.Clone(b.Contents)
+	m.bundles[b.ID] = b
+	return nil
+}
+
+func (m *MemoryRepo) DeleteBundle(ctx context.Context, id pack.ID) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	delete(m.bundles, id)
+	return nil
+}